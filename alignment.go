@@ -0,0 +1,81 @@
+package ncmt
+
+import (
+	"fmt"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// AlignmentMode controls how namespaces are placed relative to batch
+// boundaries.
+//
+// Each node of the first layer covers BatchSize/2 original leaves. A namespace
+// of c leaves that starts o leaves into a batch touches
+// ceil((o + c) / (BatchSize/2)) batches, while the minimum is
+// ceil(c / (BatchSize/2)). Every extra batch adds a full batch of sibling
+// hashes to each layer of its proof, so namespaces that start mid batch have
+// larger proofs than namespaces that start on a boundary.
+type AlignmentMode uint8
+
+const (
+	// AlignNone places each leaf directly after the last
+	AlignNone AlignmentMode = iota
+	// AlignStrict rejects pushes that would start a new namespace mid batch
+	AlignStrict
+	// AlignPadded fills the rest of the batch with padding leaves before a new
+	// namespace is started. Padding leaves belong to the preceding namespace
+	// and have an all zero payload of the same size as its last leaf, so
+	// callers that need to tell them apart from data should frame their
+	// payloads.
+	AlignPadded
+)
+
+// WithAlignment sets how namespaces are aligned to batch boundaries
+func WithAlignment(mode AlignmentMode) Option {
+	return func(o *Options) {
+		o.Alignment = mode
+	}
+}
+
+// align applies the alignment mode before a leaf of nID is pushed
+func (n *NCMT) align(nID namespace.ID) error {
+	if len(n.leaves) == 0 || n.opts.Alignment == AlignNone {
+		return nil
+	}
+	last := n.leaves[len(n.leaves)-1].data
+	if last.NamespaceID().Equal(nID) {
+		return nil
+	}
+	batchSize := n.opts.BatchSize / 2
+	offset := len(n.leaves) % batchSize
+	if offset == 0 {
+		return nil
+	}
+	switch n.opts.Alignment {
+	case AlignStrict:
+		return fmt.Errorf(
+			"invalid push: namespace must start at a batch boundary, %d leaves remain in the batch",
+			batchSize-offset,
+		)
+	case AlignPadded:
+		// check the limit up front so that a failed push never leaves the
+		// batch partially padded
+		if len(n.leaves)+batchSize-offset >= n.opts.maxLeaves() {
+			return fmt.Errorf(
+				"invalid push: padding the batch would exceed the %d leaves supported by codec %s",
+				n.opts.Codec.MaxLeaves(),
+				n.opts.Codec.ID(),
+			)
+		}
+		for i := offset; i < batchSize; i++ {
+			padding := NamespacedData{
+				ID:      append(namespace.ID{}, last.NamespaceID()...),
+				Payload: make([]byte, len(last.Data())),
+			}
+			n.appendLeaf(padding)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported alignment mode %d", n.opts.Alignment)
+	}
+}
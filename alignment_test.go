@@ -0,0 +1,77 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlignment(t *testing.T) {
+	// two leaves of namespace 1 followed by one of namespace 2, with batches
+	// of 4 originals
+	push := func(tree *NCMT) error {
+		for _, id := range []int{1, 1, 2} {
+			err := tree.Push(NewNamespacedData(mockID(id), []byte{byte(id), 9}))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	unaligned := NewNCMT(func(o *Options) { o.BatchSize = 8 })
+	assert.NoError(t, push(unaligned))
+	start, _, _ := unaligned.NamespaceRange(mockID(2))
+	assert.Equal(t, uint(2), start)
+
+	strict := NewNCMT(WithAlignment(AlignStrict), func(o *Options) { o.BatchSize = 8 })
+	assert.Error(t, push(strict))
+	assert.Len(t, strict.leaves, 2)
+
+	padded := NewNCMT(WithAlignment(AlignPadded), func(o *Options) { o.BatchSize = 8 })
+	assert.NoError(t, push(padded))
+	start, end, found := padded.NamespaceRange(mockID(1))
+	assert.True(t, found)
+	assert.Equal(t, uint(0), start)
+	assert.Equal(t, uint(4), end)
+	start, _, _ = padded.NamespaceRange(mockID(2))
+	assert.Equal(t, uint(4), start)
+	for i := uint(2); i < 4; i++ {
+		leaf, err := padded.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, mockID(1), leaf.NamespaceID())
+		assert.Equal(t, []byte{0, 0}, leaf.Data())
+	}
+
+	// pushing more of the same namespace never needs alignment
+	assert.NoError(t, strict.Push(NewNamespacedData(mockID(1), []byte{1, 9})))
+	assert.NoError(t, strict.Push(NewNamespacedData(mockID(1), []byte{1, 9})))
+	assert.NoError(t, strict.Push(NewNamespacedData(mockID(2), []byte{2, 9})))
+}
+
+func TestAlignedProofSize(t *testing.T) {
+	// namespace 2 has 2 leaves that would otherwise straddle a batch boundary
+	ids := []int{1, 2, 2, 3, 3, 3, 3, 3}
+	build := func(setters ...Option) *NCMT {
+		tree := NewNCMT(setters...)
+		for _, id := range ids {
+			assert.NoError(t, tree.Push(NewNamespacedData(mockID(id), []byte{byte(id)})))
+		}
+		for len(tree.leaves) < 16 {
+			assert.NoError(t, tree.Push(NewNamespacedData(mockID(4), []byte{4})))
+		}
+		_, err := tree.Build()
+		assert.NoError(t, err)
+		return tree
+	}
+	unaligned := build()
+	padded := build(WithAlignment(AlignPadded))
+
+	unalignedProof, err := unaligned.ProveNamespace(mockID(2))
+	assert.NoError(t, err)
+	paddedProof, err := padded.ProveNamespace(mockID(2))
+	assert.NoError(t, err)
+	assert.True(t, Verify(unaligned.Root(), nil, unalignedProof))
+	assert.True(t, Verify(padded.Root(), nil, paddedProof))
+	assert.Less(t, len(paddedProof.Set), len(unalignedProof.Set))
+}
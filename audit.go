@@ -0,0 +1,45 @@
+package ncmt
+
+import "sort"
+
+// VerifyAllNamespaces checks that proofs, keyed by namespace, are valid
+// namespace proofs for root that together cover every original leaf exactly
+// once, with no gaps or overlaps. This lets an auditor confirm that a block
+// was published in full, partitioned by namespace. Absence proofs are
+// rejected, as they cover a leaf of another namespace. If opts is nil, the
+// default options are used.
+func VerifyAllNamespaces(root []byte, opts *Options, proofs map[string]Proof) bool {
+	if opts == nil {
+		opts = NewNCMT().opts
+	}
+	if len(proofs) == 0 {
+		return false
+	}
+	ranges := make([]leafRange, 0, len(proofs))
+	var width uint
+	for id, p := range proofs {
+		if len(p.NamespaceID) == 0 || string(p.NamespaceID) != id || p.Absence() {
+			return false
+		}
+		if width == 0 {
+			width = p.Width
+		}
+		if p.Width != width || p.End > width {
+			return false
+		}
+		// verify checks that the proof holds every leaf of its namespace
+		if verify(root, opts, p) != nil {
+			return false
+		}
+		ranges = append(ranges, leafRange{start: p.Start, end: p.End})
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+	next := uint(0)
+	for _, rng := range ranges {
+		if rng.start != next {
+			return false
+		}
+		next = rng.end
+	}
+	return next == width
+}
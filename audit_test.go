@@ -0,0 +1,57 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyAllNamespaces(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	root := tree.Root()
+	proofs := make(map[string]Proof)
+	for id := 2; id <= 12; id += 2 {
+		proof, err := tree.ProveNamespace(mockID(id))
+		assert.NoError(t, err)
+		proofs[string(mockID(id))] = proof
+	}
+	assert.True(t, VerifyAllNamespaces(root, nil, proofs))
+	assert.False(t, VerifyAllNamespaces(sharedNamespaceTree(t).Root(), nil, proofs))
+	assert.False(t, VerifyAllNamespaces(root, nil, nil))
+
+	// a missing namespace leaves a gap
+	missing := copyProofs(proofs)
+	delete(missing, string(mockID(6)))
+	assert.False(t, VerifyAllNamespaces(root, nil, missing))
+	missing = copyProofs(proofs)
+	delete(missing, string(mockID(12)))
+	assert.False(t, VerifyAllNamespaces(root, nil, missing))
+
+	// absence proofs overlap the leaves of other namespaces
+	absence, err := tree.ProveNamespace(mockID(3))
+	assert.NoError(t, err)
+	overlapping := copyProofs(proofs)
+	overlapping[string(mockID(3))] = absence
+	assert.False(t, VerifyAllNamespaces(root, nil, overlapping))
+
+	// proofs must be keyed by their own namespace
+	mislabeled := copyProofs(proofs)
+	mislabeled[string(mockID(14))] = proofs[string(mockID(2))]
+	assert.False(t, VerifyAllNamespaces(root, nil, mislabeled))
+
+	// a partial proof of a namespace is not complete
+	partial := copyProofs(proofs)
+	piece := partial[string(mockID(4))]
+	piece.End--
+	piece.Data = piece.Data[:len(piece.Data)-1]
+	partial[string(mockID(4))] = piece
+	assert.False(t, VerifyAllNamespaces(root, nil, partial))
+}
+
+func copyProofs(proofs map[string]Proof) map[string]Proof {
+	copied := make(map[string]Proof, len(proofs))
+	for id, p := range proofs {
+		copied[id] = p
+	}
+	return copied
+}
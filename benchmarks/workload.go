@@ -0,0 +1,132 @@
+// Package benchmarks generates realistic block shapes and measures the ncmt
+// package against them, so that performance work has an agreed upon
+// yardstick. Results are reported in the standard benchmark format, e.g.
+//
+//	go test -run NONE -bench . -count 10 ./benchmarks > new.txt
+//	benchstat old.txt new.txt
+package benchmarks
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/evan-forbes/ncmt"
+	"github.com/evan-forbes/ncmt/blob"
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// Workload describes the shape of the blocks of a benchmark. Blob sizes, in
+// shares, follow a power law distribution, so that most blobs are small and a
+// few are large.
+type Workload struct {
+	Name          string
+	BatchSize     int
+	NamespaceSize namespace.IDSize
+	ShareSize     int
+	// Blobs is the maximum number of blobs in a block
+	Blobs int
+	// MaxShares bounds the total number of shares of a block, before padding
+	MaxShares int
+	// Exponent of the power law of blob sizes, which must be greater than 1.
+	// Larger exponents produce more small blobs.
+	Exponent float64
+}
+
+// Workloads are the shapes used by the benchmark suite. Every block fits in
+// the 128 leaves supported by the default codec once padded.
+var Workloads = []Workload{
+	{Name: "small-blobs", BatchSize: 4, NamespaceSize: 8, ShareSize: 64, Blobs: 48, MaxShares: 64, Exponent: 2.0},
+	{Name: "power-law", BatchSize: 4, NamespaceSize: 8, ShareSize: 256, Blobs: 24, MaxShares: 128, Exponent: 1.2},
+	{Name: "wide-namespaces", BatchSize: 4, NamespaceSize: 32, ShareSize: 512, Blobs: 16, MaxShares: 128, Exponent: 1.5},
+	{Name: "large-batch", BatchSize: 8, NamespaceSize: 8, ShareSize: 256, Blobs: 20, MaxShares: 64, Exponent: 1.3},
+}
+
+// Block holds generated blobs in namespace order
+type Block struct {
+	Namespaces []namespace.ID
+	Blobs      [][]byte
+}
+
+// Generate creates a block of the workload's shape. The same seed always
+// produces the same block.
+func (w Workload) Generate(seed int64) Block {
+	r := rand.New(rand.NewSource(seed))
+	zipf := rand.NewZipf(r, w.Exponent, 1, uint64(w.MaxShares-1))
+	// the first share of each blob starts with its length
+	const lengthPrefix = 4
+
+	seen := make(map[string]bool)
+	var block Block
+	total := 0
+	for i := 0; i < w.Blobs; i++ {
+		shares := 1 + int(zipf.Uint64())
+		if total+shares > w.MaxShares {
+			continue
+		}
+		total += shares
+
+		id := make(namespace.ID, w.NamespaceSize)
+		for {
+			r.Read(id)
+			// keep clear of the namespaces reserved for padding and parity
+			id[0] &= 0x7F
+			if !seen[string(id)] {
+				break
+			}
+		}
+		seen[string(id)] = true
+
+		// leave the last share of the blob partially filled
+		size := shares*w.ShareSize - lengthPrefix - r.Intn(w.ShareSize/2)
+		if size < 0 {
+			size = 0
+		}
+		data := make([]byte, size)
+		r.Read(data)
+		block.Namespaces = append(block.Namespaces, id)
+		block.Blobs = append(block.Blobs, data)
+	}
+	sort.Sort(byNamespace(block))
+	return block
+}
+
+// Option configures a tree with the batch and namespace sizes of the workload
+func (w Workload) Option() ncmt.Option {
+	return func(o *ncmt.Options) {
+		o.BatchSize = w.BatchSize
+		o.NamespaceSize = w.NamespaceSize
+	}
+}
+
+// Build commits to every blob of the block
+func (w Workload) Build(block Block) (*blob.Tree, error) {
+	tree := blob.New(w.ShareSize, w.Option())
+	for i, id := range block.Namespaces {
+		err := tree.PutBlob(id, block.Blobs[i])
+		if err != nil {
+			return nil, fmt.Errorf("workload %s: %s", w.Name, err)
+		}
+	}
+	_, err := tree.Build()
+	if err != nil {
+		return nil, fmt.Errorf("workload %s: %s", w.Name, err)
+	}
+	return tree, nil
+}
+
+type byNamespace Block
+
+func (b byNamespace) Len() int {
+	return len(b.Namespaces)
+}
+
+func (b byNamespace) Less(i, j int) bool {
+	return bytes.Compare(b.Namespaces[i], b.Namespaces[j]) < 0
+}
+
+func (b byNamespace) Swap(i, j int) {
+	b.Namespaces[i], b.Namespaces[j] = b.Namespaces[j], b.Namespaces[i]
+	b.Blobs[i], b.Blobs[j] = b.Blobs[j], b.Blobs[i]
+}
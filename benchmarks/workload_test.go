@@ -0,0 +1,175 @@
+package benchmarks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/evan-forbes/ncmt"
+	"github.com/evan-forbes/ncmt/blob"
+	"github.com/stretchr/testify/assert"
+)
+
+const seed = 42
+
+func TestWorkloads(t *testing.T) {
+	for _, w := range Workloads {
+		block := w.Generate(seed)
+		assert.NotEmpty(t, block.Blobs, w.Name)
+		assert.Equal(t, block, w.Generate(seed), w.Name)
+		tree, err := w.Build(block)
+		assert.NoError(t, err, w.Name)
+		for i, id := range block.Namespaces {
+			data, err := tree.GetBlob(id)
+			assert.NoError(t, err)
+			assert.Equal(t, block.Blobs[i], data)
+		}
+	}
+}
+
+// built is a workload's block committed to, along with the parameters
+// shared by the benchmarks
+type built struct {
+	block Block
+	tree  *ncmt.NCMT
+	opts  ncmt.Options
+	// median is the namespace of the middle blob
+	median []byte
+}
+
+func build(b *testing.B, w Workload) built {
+	block := w.Generate(seed)
+	tree, err := w.Build(block)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return built{
+		block:  block,
+		tree:   tree.NCMT(),
+		opts:   tree.NCMT().Options(),
+		median: block.Namespaces[len(block.Namespaces)/2],
+	}
+}
+
+// forEachWorkload runs fn as a sub benchmark of every workload, reporting the
+// number of leaves in the tree
+func forEachWorkload(b *testing.B, fn func(b *testing.B, w Workload, bt built)) {
+	for _, w := range Workloads {
+		bt := build(b, w)
+		b.Run(fmt.Sprintf("workload=%s", w.Name), func(b *testing.B) {
+			b.ReportAllocs()
+			fn(b, w, bt)
+			b.ReportMetric(float64(bt.tree.OriginalWidth()), "leaves")
+		})
+	}
+}
+
+func BenchmarkBuild(b *testing.B) {
+	forEachWorkload(b, func(b *testing.B, w Workload, bt built) {
+		for i := 0; i < b.N; i++ {
+			tree := blob.New(w.ShareSize, w.Option())
+			for j, id := range bt.block.Namespaces {
+				tree.PutBlob(id, bt.block.Blobs[j])
+			}
+			_, err := tree.Build()
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkProveNamespace(b *testing.B) {
+	forEachWorkload(b, func(b *testing.B, w Workload, bt built) {
+		var proof ncmt.Proof
+		var err error
+		for i := 0; i < b.N; i++ {
+			proof, err = bt.tree.ProveNamespace(bt.median)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		raw, err := proof.MarshalCBOR()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportMetric(float64(len(raw)), "proof-B")
+	})
+}
+
+func BenchmarkVerify(b *testing.B) {
+	forEachWorkload(b, func(b *testing.B, w Workload, bt built) {
+		proof, err := bt.tree.ProveNamespace(bt.median)
+		if err != nil {
+			b.Fatal(err)
+		}
+		root := bt.tree.Root()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if !ncmt.Verify(root, &bt.opts, proof) {
+				b.Fatal("invalid proof")
+			}
+		}
+	})
+}
+
+func BenchmarkSample(b *testing.B) {
+	forEachWorkload(b, func(b *testing.B, w Workload, bt built) {
+		// a light client's worth of samples spread across the extended leaves
+		width := bt.tree.ExtendedWidth()
+		indices := make([]uint, 16)
+		for i := range indices {
+			indices[i] = uint(i) * width / uint(len(indices))
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, err := bt.tree.Sample(indices)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkSamplePooled(b *testing.B) {
+	forEachWorkload(b, func(b *testing.B, w Workload, bt built) {
+		width := bt.tree.ExtendedWidth()
+		indices := make([]uint, 16)
+		for i := range indices {
+			indices[i] = uint(i) * width / uint(len(indices))
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			samples, err := bt.tree.SamplePooled(indices)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, sample := range samples {
+				sample.Release()
+			}
+		}
+	})
+}
+
+func BenchmarkRepair(b *testing.B) {
+	forEachWorkload(b, func(b *testing.B, w Workload, bt built) {
+		// repair from the parity half alone, the worst case for decoding
+		width := bt.tree.OriginalWidth()
+		indices := make([]uint, width)
+		for i := range indices {
+			indices[i] = width + uint(i)
+		}
+		samples, err := bt.tree.Sample(indices)
+		if err != nil {
+			b.Fatal(err)
+		}
+		root := bt.tree.Root()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, err := ncmt.Repair(root, &bt.opts, samples)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
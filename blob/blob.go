@@ -0,0 +1,183 @@
+// Package blob provides a high level API for committing to variable length
+// blobs in an NCMT. Each blob is split into fixed size shares under its own
+// namespace, while the ncmt package remains available for full control over
+// leaves, layers, codecs and proofs.
+package blob
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/evan-forbes/ncmt"
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// Tree collects blobs and commits to them using an NCMT
+type Tree struct {
+	tree      *ncmt.NCMT
+	opts      ncmt.Options
+	shareSize int
+	blobs     map[string][]byte
+	built     bool
+}
+
+// New issues a new Tree that splits blobs into shares of shareSize bytes,
+// using the provided options for the underlying NCMT
+func New(shareSize int, setters ...ncmt.Option) *Tree {
+	tree := ncmt.NewNCMT(setters...)
+	return &Tree{
+		tree:      tree,
+		opts:      tree.Options(),
+		shareSize: shareSize,
+		blobs:     make(map[string][]byte),
+	}
+}
+
+// PutBlob adds a blob under nID. Each namespace can hold a single blob, and
+// blobs cannot be added after Build.
+func (t *Tree) PutBlob(nID namespace.ID, data []byte) error {
+	if t.built {
+		return errors.New("cannot add blobs to a built tree")
+	}
+	if nID.Size() != t.opts.NamespaceSize {
+		return fmt.Errorf(
+			"invalid namespace: expected size %d, received size %d",
+			t.opts.NamespaceSize,
+			nID.Size(),
+		)
+	}
+	if nID.Equal(PaddingNamespace(t.opts.NamespaceSize)) {
+		return errors.New("invalid namespace: reserved for padding")
+	}
+	if _, has := t.blobs[string(nID)]; has {
+		return fmt.Errorf("namespace %s already has a blob", ncmt.FormatNamespace(nID))
+	}
+	t.blobs[string(nID)] = append([]byte{}, data...)
+	return nil
+}
+
+// Build splits every blob into shares, pushes them in namespace order, pads
+// the shares with padding shares to a width that can be built, and builds the
+// tree
+func (t *Tree) Build() ([]byte, error) {
+	if t.built {
+		return t.tree.Root(), nil
+	}
+	ids := make([]string, 0, len(t.blobs))
+	for id := range t.blobs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare([]byte(ids[i]), []byte(ids[j])) < 0
+	})
+
+	count := 0
+	for _, id := range ids {
+		shares, err := Split(t.blobs[id], t.shareSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			err = t.tree.Push(ncmt.NamespacedData{ID: namespace.ID(id), Payload: share})
+			if err != nil {
+				return nil, err
+			}
+			count++
+		}
+	}
+
+	// pad with empty shares so that every leaf can be batched
+	if count == 0 {
+		err := t.tree.Push(ncmt.PaddingShare(t.opts.NamespaceSize, t.shareSize))
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := t.tree.AutoPad(t.shareSize)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := t.tree.Build()
+	if err != nil {
+		return nil, err
+	}
+	t.built = true
+	return root, nil
+}
+
+// GetBlob reassembles the blob stored under nID from the leaves of the built
+// tree
+func (t *Tree) GetBlob(nID namespace.ID) ([]byte, error) {
+	if !t.built {
+		return nil, errors.New("tree must be built before retrieving blobs")
+	}
+	start, end, found := t.tree.NamespaceRange(nID)
+	if !found || nID.Equal(PaddingNamespace(t.opts.NamespaceSize)) {
+		return nil, fmt.Errorf("namespace not found in tree: %s", ncmt.FormatNamespace(nID))
+	}
+	shares := make([][]byte, 0, end-start)
+	for i := start; i < end; i++ {
+		data, err := t.tree.Get(i)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, data.Data())
+	}
+	return Join(shares)
+}
+
+// ProveBlob creates a namespace proof of every share of the blob stored under
+// nID. The shares are the leaves [Start, End) of the proof, and VerifyBlob
+// reassembles the blob from it.
+func (t *Tree) ProveBlob(nID namespace.ID) (ncmt.Proof, error) {
+	if !t.built {
+		return ncmt.Proof{}, errors.New("tree must be built before proving blobs")
+	}
+	_, _, found := t.tree.NamespaceRange(nID)
+	if !found || nID.Equal(PaddingNamespace(t.opts.NamespaceSize)) {
+		return ncmt.Proof{}, fmt.Errorf("namespace not found in tree: %s", ncmt.FormatNamespace(nID))
+	}
+	return t.tree.ProveNamespace(nID)
+}
+
+// VerifyBlob checks that proof holds every share of the blob under nID in the
+// tree committed to by root, and returns the blob. If opts is nil, the default
+// options are used.
+func VerifyBlob(root []byte, opts *ncmt.Options, nID namespace.ID, proof ncmt.Proof) ([]byte, error) {
+	if !proof.NamespaceID.Equal(nID) {
+		return nil, fmt.Errorf("proof is for namespace %s", ncmt.FormatNamespace(proof.NamespaceID))
+	}
+	if proof.Absence() {
+		return nil, fmt.Errorf("namespace not found in tree: %s", ncmt.FormatNamespace(nID))
+	}
+	if proof.HashesOnly() {
+		return nil, errors.New("proof does not hold the data of the blob")
+	}
+	if !ncmt.Verify(root, opts, proof) {
+		return nil, errors.New("invalid blob proof")
+	}
+	shares := make([][]byte, len(proof.Data))
+	for i, data := range proof.Data {
+		shares[i] = data[len(nID):]
+	}
+	return Join(shares)
+}
+
+// Root returns the root of the underlying tree
+func (t *Tree) Root() []byte {
+	return t.tree.Root()
+}
+
+// NCMT returns the underlying tree for low level access
+func (t *Tree) NCMT() *ncmt.NCMT {
+	return t.tree
+}
+
+// PaddingNamespace is the namespace reserved for padding shares. See
+// ncmt.PaddingNamespace.
+func PaddingNamespace(size namespace.IDSize) namespace.ID {
+	return ncmt.PaddingNamespace(size)
+}
@@ -0,0 +1,99 @@
+package blob
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lazyledger/nmt/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitJoin(t *testing.T) {
+	for _, size := range []int{0, 1, 11, 12, 13, 100} {
+		data := bytes.Repeat([]byte{7}, size)
+		shares, err := Split(data, 16)
+		assert.NoError(t, err)
+		for _, share := range shares {
+			assert.Len(t, share, 16)
+		}
+		joined, err := Join(shares)
+		assert.NoError(t, err)
+		assert.Equal(t, data, joined)
+	}
+
+	_, err := Split([]byte{1}, 0)
+	assert.Error(t, err)
+	_, err = Join([][]byte{{0, 0, 0, 9, 1}})
+	assert.Error(t, err)
+}
+
+func TestTree(t *testing.T) {
+	blobs := map[byte][]byte{
+		3: bytes.Repeat([]byte{3}, 40),
+		1: []byte("hello"),
+		2: bytes.Repeat([]byte{2}, 100),
+	}
+	tree := New(32)
+	for id, data := range blobs {
+		assert.NoError(t, tree.PutBlob(mockID(id), data))
+	}
+	assert.Error(t, tree.PutBlob(mockID(1), []byte("again")))
+	assert.Error(t, tree.PutBlob(PaddingNamespace(8), []byte("padding")))
+	assert.Error(t, tree.PutBlob(namespace.ID{1}, []byte("short")))
+
+	root, err := tree.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, tree.Root(), root)
+
+	for id, data := range blobs {
+		got, err := tree.GetBlob(mockID(id))
+		assert.NoError(t, err)
+		assert.Equal(t, data, got)
+	}
+	_, err = tree.GetBlob(mockID(9))
+	assert.Error(t, err)
+	_, err = tree.GetBlob(PaddingNamespace(8))
+	assert.Error(t, err)
+	assert.Error(t, tree.PutBlob(mockID(4), []byte("late")))
+}
+
+func TestProveBlob(t *testing.T) {
+	tree := New(32)
+	_, err := tree.ProveBlob(mockID(2))
+	assert.Error(t, err)
+	assert.NoError(t, tree.PutBlob(mockID(1), []byte("hello")))
+	blob := bytes.Repeat([]byte{2}, 100)
+	assert.NoError(t, tree.PutBlob(mockID(2), blob))
+	root, err := tree.Build()
+	assert.NoError(t, err)
+	opts := tree.NCMT().Options()
+
+	proof, err := tree.ProveBlob(mockID(2))
+	assert.NoError(t, err)
+	start, end, _ := tree.NCMT().NamespaceRange(mockID(2))
+	assert.Equal(t, start, proof.Start)
+	assert.Equal(t, end, proof.End)
+	got, err := VerifyBlob(root, &opts, mockID(2), proof)
+	assert.NoError(t, err)
+	assert.Equal(t, blob, got)
+
+	// the proof must be for the requested namespace, and valid for root
+	_, err = VerifyBlob(root, &opts, mockID(1), proof)
+	assert.Error(t, err)
+	proof.Data[0][len(proof.Data[0])-1]++
+	_, err = VerifyBlob(root, &opts, mockID(2), proof)
+	assert.Error(t, err)
+
+	_, err = tree.ProveBlob(mockID(9))
+	assert.Error(t, err)
+	_, err = tree.ProveBlob(PaddingNamespace(8))
+	assert.Error(t, err)
+	absence, err := tree.NCMT().ProveNamespace(mockID(9))
+	assert.NoError(t, err)
+	_, err = VerifyBlob(root, &opts, mockID(9), absence)
+	assert.Error(t, err)
+}
+
+func mockID(id byte) namespace.ID {
+	return namespace.ID{0, 0, 0, 0, 0, 0, 0, id}
+}
@@ -0,0 +1,55 @@
+package blob
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// lengthPrefixSize is the number of bytes used to encode the length of a blob
+// at the start of its first share
+const lengthPrefixSize = 4
+
+// Split divides data into shares of shareSize bytes. The first share starts
+// with the length of data as a big endian uint32, and the last share is zero
+// padded.
+func Split(data []byte, shareSize int) ([][]byte, error) {
+	if shareSize <= 0 {
+		return nil, fmt.Errorf("invalid share size %d", shareSize)
+	}
+	if uint64(len(data)) > uint64(^uint32(0)) {
+		return nil, errors.New("blob is too large to be split")
+	}
+	prefixed := make([]byte, lengthPrefixSize, lengthPrefixSize+len(data))
+	binary.BigEndian.PutUint32(prefixed, uint32(len(data)))
+	prefixed = append(prefixed, data...)
+
+	count := (len(prefixed) + shareSize - 1) / shareSize
+	shares := make([][]byte, count)
+	for i := range shares {
+		share := make([]byte, shareSize)
+		copy(share, prefixed[i*shareSize:])
+		shares[i] = share
+	}
+	return shares, nil
+}
+
+// Join reverses Split
+func Join(shares [][]byte) ([]byte, error) {
+	var joined []byte
+	for _, share := range shares {
+		joined = append(joined, share...)
+	}
+	if len(joined) < lengthPrefixSize {
+		return nil, errors.New("shares are too short to contain a blob")
+	}
+	size := binary.BigEndian.Uint32(joined)
+	if uint64(size) > uint64(len(joined)-lengthPrefixSize) {
+		return nil, fmt.Errorf(
+			"blob length %d is greater than the %d bytes available",
+			size,
+			len(joined)-lengthPrefixSize,
+		)
+	}
+	return joined[lengthPrefixSize : lengthPrefixSize+int(size)], nil
+}
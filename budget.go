@@ -0,0 +1,139 @@
+package ncmt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// ContinuationToken resumes a namespace proof that did not fit in a single
+// byte budget
+type ContinuationToken struct {
+	Root        []byte       `cbor:"root"`
+	NamespaceID namespace.ID `cbor:"namespace_id"`
+	// Next is the first leaf not yet proven
+	Next uint `cbor:"next"`
+}
+
+// ProveNamespaceWithBudget creates a namespace proof whose CBOR encoding is at
+// most maxBytes. If the full proof does not fit, a prefix of the namespace's
+// leaves that fits is proven instead, and a token to continue from is
+// returned. The token is nil once the namespace is fully proven. Pieces are
+// verified together using VerifyNamespacePieces.
+func (n *NCMT) ProveNamespaceWithBudget(nID namespace.ID, maxBytes int) (Proof, *ContinuationToken, error) {
+	full, err := n.ProveNamespace(nID)
+	if err != nil {
+		return Proof{}, nil, err
+	}
+	size, err := proofSize(full)
+	if err != nil {
+		return Proof{}, nil, err
+	}
+	if size <= maxBytes {
+		return full, nil, nil
+	}
+	if full.Absence() {
+		return Proof{}, nil, fmt.Errorf("absence proof of %d bytes exceeds the budget of %d bytes", size, maxBytes)
+	}
+	return n.provePiece(nID, full.Start, full.End, maxBytes)
+}
+
+// ContinueNamespaceProof creates the next piece of a namespace proof started by
+// ProveNamespaceWithBudget
+func (n *NCMT) ContinueNamespaceProof(token ContinuationToken, maxBytes int) (Proof, *ContinuationToken, error) {
+	if len(n.layers) == 0 {
+		return Proof{}, nil, errors.New("tree must be built before creating proofs")
+	}
+	if !bytes.Equal(token.Root, n.Root()) {
+		return Proof{}, nil, errors.New("continuation token belongs to a different tree")
+	}
+	start, end, found := n.NamespaceRange(token.NamespaceID)
+	if !found || token.Next <= start || token.Next >= end {
+		return Proof{}, nil, errors.New("continuation token is outside of the namespace")
+	}
+	return n.provePiece(token.NamespaceID, token.Next, end, maxBytes)
+}
+
+// provePiece proves a range [start, k) of the namespace's leaves [start, end)
+// that fits within maxBytes. The size of a proof generally grows with its
+// range, as each leaf adds its data while removing at most a few sibling
+// hashes, so k is found by binary search instead of proving every prefix.
+func (n *NCMT) provePiece(nID namespace.ID, start, end uint, maxBytes int) (Proof, *ContinuationToken, error) {
+	var piece Proof
+	// piece holds the last prefix found to fit, which is shorter than low,
+	// while the prefix ending at high, if any, did not fit
+	low, high := start+1, end+1
+	for low < high {
+		k := low + (high-low)/2
+		candidate, err := n.prove(start, k)
+		if err != nil {
+			return Proof{}, nil, err
+		}
+		candidate.NamespaceID = append(namespace.ID{}, nID...)
+		size, err := proofSize(candidate)
+		if err != nil {
+			return Proof{}, nil, err
+		}
+		if size > maxBytes {
+			high = k
+			continue
+		}
+		piece = candidate
+		low = k + 1
+	}
+	if piece.End == 0 {
+		return Proof{}, nil, fmt.Errorf("a single leaf of the namespace does not fit in %d bytes", maxBytes)
+	}
+	if piece.End == end {
+		return piece, nil, nil
+	}
+	return piece, &ContinuationToken{
+		Root:        n.Root(),
+		NamespaceID: append(namespace.ID{}, nID...),
+		Next:        piece.End,
+	}, nil
+}
+
+// proofSize is the length of the CBOR encoding of p
+func proofSize(p Proof) (int, error) {
+	raw, err := p.MarshalCBOR()
+	if err != nil {
+		return 0, err
+	}
+	return len(raw), nil
+}
+
+// VerifyNamespacePieces checks that pieces, in order, prove every leaf of
+// their namespace, or its absence. A single piece must be a complete namespace
+// proof. If opts is nil, the default options are used.
+func VerifyNamespacePieces(root []byte, opts *Options, pieces []Proof) bool {
+	if opts == nil {
+		opts = NewNCMT().opts
+	}
+	if len(pieces) == 0 {
+		return false
+	}
+	first := pieces[0]
+	if len(first.NamespaceID) == 0 {
+		return false
+	}
+	if len(pieces) == 1 {
+		return verify(root, opts, first) == nil
+	}
+	for i, piece := range pieces {
+		if !piece.NamespaceID.Equal(first.NamespaceID) || piece.Width != first.Width || piece.Absence() {
+			return false
+		}
+		if i > 0 && piece.Start != pieces[i-1].End {
+			return false
+		}
+		// only the ends of the namespace are checked for completeness
+		err := verifySides(root, opts, piece, i == 0, i == len(pieces)-1)
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
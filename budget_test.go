@@ -0,0 +1,64 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProveNamespaceWithBudget(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	root := tree.Root()
+
+	full, err := tree.ProveNamespace(mockID(4))
+	assert.NoError(t, err)
+	fullSize, err := proofSize(full)
+	assert.NoError(t, err)
+
+	// the full proof is returned if it fits
+	proof, token, err := tree.ProveNamespaceWithBudget(mockID(4), fullSize)
+	assert.NoError(t, err)
+	assert.Nil(t, token)
+	assert.Equal(t, full, proof)
+	assert.True(t, VerifyNamespacePieces(root, nil, []Proof{proof}))
+
+	// otherwise it is split into pieces
+	budget := fullSize - 1
+	proof, token, err = tree.ProveNamespaceWithBudget(mockID(4), budget)
+	assert.NoError(t, err)
+	pieces := []Proof{proof}
+	for token != nil {
+		proof, token, err = tree.ContinueNamespaceProof(*token, budget)
+		assert.NoError(t, err)
+		pieces = append(pieces, proof)
+	}
+	assert.Greater(t, len(pieces), 1)
+	for _, piece := range pieces {
+		size, err := proofSize(piece)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, size, budget)
+		// no piece is complete on its own
+		assert.False(t, Verify(root, nil, piece))
+	}
+	assert.Equal(t, full.Start, pieces[0].Start)
+	assert.Equal(t, full.End, pieces[len(pieces)-1].End)
+	assert.True(t, VerifyNamespacePieces(root, nil, pieces))
+
+	// missing or reordered pieces fail
+	assert.False(t, VerifyNamespacePieces(root, nil, pieces[1:]))
+	assert.False(t, VerifyNamespacePieces(root, nil, pieces[:len(pieces)-1]))
+	reordered := append([]Proof{pieces[len(pieces)-1]}, pieces[:len(pieces)-1]...)
+	assert.False(t, VerifyNamespacePieces(root, nil, reordered))
+
+	// tokens are bound to their tree
+	_, token, err = tree.ProveNamespaceWithBudget(mockID(4), budget)
+	assert.NoError(t, err)
+	_, _, err = sharedNamespaceTree(t).ContinueNamespaceProof(*token, budget)
+	assert.Error(t, err)
+
+	// budgets too small for a single leaf
+	_, _, err = tree.ProveNamespaceWithBudget(mockID(4), 10)
+	assert.Error(t, err)
+	_, _, err = tree.ProveNamespaceWithBudget(mockID(5), 10)
+	assert.Error(t, err)
+}
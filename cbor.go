@@ -0,0 +1,104 @@
+package ncmt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// cborEncMode encodes deterministically using the dag-cbor subset of CBOR:
+// definite lengths, minimal integers, and map keys sorted length first.
+var cborEncMode = mustCBOREncMode()
+
+// cborDecMode rejects duplicate map keys, indefinite lengths and tags. It
+// accepts other non canonical encodings, which UnmarshalCanonical rejects.
+var cborDecMode = mustCBORDecMode()
+
+func mustCBOREncMode() cbor.EncMode {
+	opts := cbor.CanonicalEncOptions()
+	opts.IndefLength = cbor.IndefLengthForbidden
+	opts.TagsMd = cbor.TagsForbidden
+	mode, err := opts.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}
+
+func mustCBORDecMode() cbor.DecMode {
+	mode, err := cbor.DecOptions{
+		DupMapKey:   cbor.DupMapKeyEnforcedAPF,
+		IndefLength: cbor.IndefLengthForbidden,
+		TagsMd:      cbor.TagsForbidden,
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}
+
+// MarshalCanonical encodes v in the same deterministic dag-cbor subset as
+// proofs and headers, so that other packages can define types with a single
+// encoding that embed them
+func MarshalCanonical(v interface{}) ([]byte, error) {
+	return cborEncMode.Marshal(v)
+}
+
+// UnmarshalCanonical decodes data into v, rejecting any input that differs
+// from the encoding MarshalCanonical produces for the decoded value, such as
+// non minimal integers, unsorted or unknown keys, so that every value has
+// exactly one accepted encoding
+func UnmarshalCanonical(data []byte, v interface{}) error {
+	err := cborDecMode.Unmarshal(data, v)
+	if err != nil {
+		return err
+	}
+	canonical, err := cborEncMode.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(canonical, data) {
+		return errors.New("encoding is not canonical")
+	}
+	return nil
+}
+
+// cborProof prevents MarshalCBOR and UnmarshalCBOR from recursing
+type cborProof Proof
+
+// MarshalCBOR deterministically encodes the proof as dag-cbor
+func (p Proof) MarshalCBOR() ([]byte, error) {
+	return cborEncMode.Marshal(cborProof(p))
+}
+
+// UnmarshalCBOR decodes a proof encoded by MarshalCBOR
+func (p *Proof) UnmarshalCBOR(data []byte) error {
+	var decoded cborProof
+	err := UnmarshalCanonical(data, &decoded)
+	if err != nil {
+		return fmt.Errorf("failure to decode proof: %s", err)
+	}
+	*p = Proof(decoded)
+	return nil
+}
+
+// cborHeader prevents MarshalCBOR and UnmarshalCBOR from recursing
+type cborHeader TreeHeader
+
+// MarshalCBOR deterministically encodes the header as dag-cbor
+func (h TreeHeader) MarshalCBOR() ([]byte, error) {
+	return cborEncMode.Marshal(cborHeader(h))
+}
+
+// UnmarshalCBOR decodes a header encoded by MarshalCBOR
+func (h *TreeHeader) UnmarshalCBOR(data []byte) error {
+	var decoded cborHeader
+	err := UnmarshalCanonical(data, &decoded)
+	if err != nil {
+		return fmt.Errorf("failure to decode header: %s", err)
+	}
+	*h = TreeHeader(decoded)
+	return nil
+}
@@ -0,0 +1,85 @@
+package ncmt
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProofCBOR(t *testing.T) {
+	proof := Proof{
+		Start:       1,
+		End:         2,
+		Width:       300,
+		Data:        [][]byte{{1, 9}},
+		Set:         [][]byte{{1, 2}, {3}},
+		Counts:      [][2]uint64{{1, 2}},
+		NamespaceID: []byte{1},
+	}
+	raw, err := proof.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keys are sorted by length, then bytewise, and integers are minimal
+	expected := "a7" + // map of 7 pairs
+		"63656e64" + "02" + // "end": 2
+		"63736574" + "824201024103" + // "set": [h'0102', h'03']
+		"6464617461" + "81420109" + // "data": [h'0109']
+		"657374617274" + "01" + // "start": 1
+		"657769647468" + "19012c" + // "width": 300
+		"66636f756e7473" + "81820102" + // "counts": [[1, 2]]
+		"6c6e616d6573706163655f6964" + "4101" // "namespace_id": h'01'
+	assert.Equal(t, expected, hex.EncodeToString(raw))
+
+	var decoded Proof
+	assert.NoError(t, decoded.UnmarshalCBOR(raw))
+	assert.Equal(t, proof, decoded)
+
+	// duplicate keys and indefinite lengths are rejected
+	duplicate, _ := hex.DecodeString("a2" + "6369647802" + "6369647802")
+	assert.Error(t, decoded.UnmarshalCBOR(duplicate))
+	indefinite, _ := hex.DecodeString("bf" + "63656e64" + "02" + "ff")
+	assert.Error(t, decoded.UnmarshalCBOR(indefinite))
+
+	// as is any other encoding that differs from MarshalCBOR's
+	body := expected[len("a7"):]
+	endPair, setPair := "63656e64"+"02", "63736574"+"824201024103"
+	for _, malleated := range []string{
+		"a7" + strings.Replace(body, endPair, "63656e64"+"1802", 1), // non minimal integer
+		"a7" + setPair + endPair + body[len(endPair+setPair):],      // unsorted keys
+		"a8" + body + "6e" + strings.Repeat("7a", 14) + "01",        // unknown key
+		expected + "00", // trailing data
+	} {
+		raw, _ := hex.DecodeString(malleated)
+		assert.Error(t, decoded.UnmarshalCBOR(raw), malleated)
+	}
+
+	header := TreeHeader{Root: []byte{1}, BatchSize: 4}
+	raw, err = header.MarshalCBOR()
+	assert.NoError(t, err)
+	var decodedHeader TreeHeader
+	assert.NoError(t, decodedHeader.UnmarshalCBOR(raw))
+	assert.Equal(t, header, decodedHeader)
+	assert.Error(t, decodedHeader.UnmarshalCBOR(append(raw, 0)))
+}
+
+func TestCanonical(t *testing.T) {
+	type wrapper struct {
+		Proofs []Proof `cbor:"proofs"`
+		Count  uint64  `cbor:"count"`
+	}
+	w := wrapper{Proofs: []Proof{{Start: 1, End: 2, Width: 4}}, Count: 3}
+	raw, err := MarshalCanonical(w)
+	assert.NoError(t, err)
+	var decoded wrapper
+	assert.NoError(t, UnmarshalCanonical(raw, &decoded))
+	assert.Equal(t, w, decoded)
+
+	// the count encoded as a non minimal integer is rejected
+	malleated := strings.Replace(hex.EncodeToString(raw), "65636f756e7403", "65636f756e741803", 1)
+	assert.NotEqual(t, hex.EncodeToString(raw), malleated)
+	bad, _ := hex.DecodeString(malleated)
+	assert.Error(t, UnmarshalCanonical(bad, &decoded))
+}
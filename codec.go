@@ -7,8 +7,31 @@ type Codec interface {
 	Encode([][]byte) ([][]byte, error)
 	Decode([][]byte) ([][]byte, error)
 	MaxLeaves() int
+	// ID uniquely identifies the encoding scheme. Codecs with the same ID must
+	// produce identical output.
+	ID() string
 }
 
+// ConcurrentCodec is implemented by codecs whose Encode and Decode can be called
+// from multiple goroutines at once. Independent segments of a layer are only
+// erasured in parallel if the codec is concurrent.
+type ConcurrentCodec interface {
+	Codec
+	Concurrent() bool
+}
+
+// codecWorkers limits workers to a single goroutine unless c is concurrent
+func codecWorkers(c Codec, workers int) int {
+	cc, ok := c.(ConcurrentCodec)
+	if !ok || !cc.Concurrent() {
+		return 1
+	}
+	return workers
+}
+
+// CodecRSGF8 is the ID of RSFG8
+const CodecRSGF8 = "RSGF8"
+
 // RSFG8 uses the rsmt2d cached version of the infectious Reed-Solomon forward error
 // correction implementation. Not thread safe.
 type RSFG8 struct{}
@@ -28,3 +51,7 @@ func (r RSFG8) Decode(input [][]byte) ([][]byte, error) {
 func (r RSFG8) MaxLeaves() int {
 	return 128
 }
+
+func (r RSFG8) ID() string {
+	return CodecRSGF8
+}
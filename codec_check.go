@@ -0,0 +1,114 @@
+package ncmt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownCodec is returned by CheckCodec when no expected output has been
+// registered for a codec's ID
+var ErrUnknownCodec = errors.New("no codec vector registered for codec")
+
+// codecVectors maps codec IDs to the expected encoding of codecCheckInput
+var codecVectors = struct {
+	sync.RWMutex
+	expected map[string][][]byte
+}{
+	expected: map[string][][]byte{
+		RSFG8{}.ID(): mustDecodeHex(
+			"a5a6a7a0a1a2a3a7",
+			"6063626564676635",
+			"1d1e1f18191a1b5b",
+			"0c0f0e09080b0a57",
+			"82818087868584be",
+			"52515057565554b8",
+			"171415121310113d",
+			"989b9a9d9c9f9e22",
+		),
+	},
+}
+
+// codecCheckInput returns 8 shares of 8 bytes, where each byte is its position
+// in the flattened input plus one
+func codecCheckInput() [][]byte {
+	input := make([][]byte, 8)
+	for i := range input {
+		input[i] = make([]byte, 8)
+		for j := range input[i] {
+			input[i][j] = byte(i*8 + j + 1)
+		}
+	}
+	return input
+}
+
+// RegisterCodecVector stores the expected encoding of the fixed codec check
+// input for a custom codec, so that it can be checked by CheckCodec
+func RegisterCodecVector(id string, expected [][]byte) {
+	codecVectors.Lock()
+	defer codecVectors.Unlock()
+	codecVectors.expected[id] = expected
+}
+
+// CheckCodec encodes a fixed input with c and compares the result to the
+// output registered for c's ID. It also checks that decoding from only the
+// erasured half recovers the input. This catches nondeterministic or
+// mislinked codecs before they produce roots that diverge from the rest of
+// the network.
+func CheckCodec(c Codec) error {
+	codecVectors.RLock()
+	expected, found := codecVectors.expected[c.ID()]
+	codecVectors.RUnlock()
+	if !found {
+		return fmt.Errorf("%w: %s", ErrUnknownCodec, c.ID())
+	}
+
+	input := codecCheckInput()
+	encoded, err := c.Encode(input)
+	if err != nil {
+		return fmt.Errorf("codec %s failed to encode check input: %s", c.ID(), err)
+	}
+	if !equalShares(expected, encoded) {
+		return fmt.Errorf("codec %s produced unexpected output for the check input", c.ID())
+	}
+
+	// decode using only the erasured half
+	partial := make([][]byte, 2*len(input))
+	for i, share := range encoded {
+		partial[len(input)+i] = append([]byte{}, share...)
+	}
+	decoded, err := c.Decode(partial)
+	if err != nil {
+		return fmt.Errorf("codec %s failed to decode check input: %s", c.ID(), err)
+	}
+	if len(decoded) < len(input) || !equalShares(input, decoded[:len(input)]) {
+		return fmt.Errorf("codec %s did not recover the check input", c.ID())
+	}
+	return nil
+}
+
+func equalShares(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustDecodeHex(shares ...string) [][]byte {
+	out := make([][]byte, len(shares))
+	for i, share := range shares {
+		raw, err := hex.DecodeString(share)
+		if err != nil {
+			panic(err)
+		}
+		out[i] = raw
+	}
+	return out
+}
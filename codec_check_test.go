@@ -0,0 +1,46 @@
+package ncmt
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyCodec wraps RSFG8 but alters the output
+type flakyCodec struct {
+	RSFG8
+	id string
+}
+
+func (f flakyCodec) Encode(input [][]byte) ([][]byte, error) {
+	out, err := f.RSFG8.Encode(input)
+	if err != nil {
+		return nil, err
+	}
+	out[0][0]++
+	return out, nil
+}
+
+func (f flakyCodec) ID() string {
+	return f.id
+}
+
+func TestCheckCodec(t *testing.T) {
+	assert.NoError(t, CheckCodec(RSFG8{}))
+
+	// a codec claiming to be RSFG8 but producing different output fails
+	assert.Error(t, CheckCodec(flakyCodec{id: RSFG8{}.ID()}))
+
+	// unknown codecs must be registered first
+	err := CheckCodec(flakyCodec{id: "flaky"})
+	assert.True(t, errors.Is(err, ErrUnknownCodec))
+
+	// check using the codec as part of Build
+	tree := NewNCMT(WithCodec(flakyCodec{id: RSFG8{}.ID()}), WithCodecCheck())
+	for _, d := range mockData(4, 4) {
+		assert.NoError(t, tree.Push(d))
+	}
+	_, err = tree.Build()
+	assert.Error(t, err)
+}
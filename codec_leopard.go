@@ -0,0 +1,137 @@
+//go:build leopard
+
+package ncmt
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lazyledger/rsmt2d"
+)
+
+// CodecLeopardFF16 is the ID of LeopardFF16
+const CodecLeopardFF16 = "LeopardFF16"
+
+func init() {
+	// with 8 originals, leopard erasures over GF(2^8) in its Cantor basis
+	RegisterCodecVector(CodecLeopardFF16, mustDecodeHex(
+		"424140474645446d",
+		"4a49484f4e4d4cb1",
+		"52515057565554b6",
+		"5a59585f5e5d5ce2",
+		"62616067666564db",
+		"6a69686f6e6d6c8b",
+		"727170777675747e",
+		"7a79787f7e7d7ce6",
+	))
+}
+
+// LeopardFF16 uses the rsmt2d wrapper of the leopard Reed-Solomon
+// implementation over GF(2^16), which supports far more leaves than RSFG8.
+// Shares must have an even number of bytes. Requires cgo and the leopard
+// build tag.
+type LeopardFF16 struct{}
+
+func (l LeopardFF16) Encode(input [][]byte) ([][]byte, error) {
+	size, err := leopardShareSize(input)
+	if err != nil {
+		return nil, err
+	}
+	parity, err := rsmt2d.Encode(leopardPack(input, size), rsmt2d.LeopardFF16)
+	if err != nil {
+		return nil, err
+	}
+	return leopardUnpack(parity, size), nil
+}
+
+func (l LeopardFF16) Decode(input [][]byte) ([][]byte, error) {
+	size, err := leopardShareSize(input)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := rsmt2d.Decode(leopardPack(input, size), rsmt2d.LeopardFF16)
+	if err != nil {
+		return nil, err
+	}
+	return leopardUnpack(decoded, size), nil
+}
+
+func (l LeopardFF16) MaxLeaves() int {
+	return 32768
+}
+
+func (l LeopardFF16) ID() string {
+	return CodecLeopardFF16
+}
+
+// Concurrent fulfills ConcurrentCodec. Leopard keeps no state between calls.
+func (l LeopardFF16) Concurrent() bool {
+	return true
+}
+
+/////////////////////////////////////////
+//  Share layout
+///////////////////////////////////////
+
+// leopardBlock is the size leopard requires shares to be a multiple of. Each
+// 16 bit symbol takes a byte from the first and second half of a block.
+const leopardBlock = 64
+
+// leopardShareSize returns the size of the shares that are present, which must
+// be equal, even and non zero
+func leopardShareSize(shares [][]byte) (int, error) {
+	size := 0
+	for _, share := range shares {
+		if share == nil {
+			continue
+		}
+		if size != 0 && len(share) != size {
+			return 0, errors.New("leopard shares must be of equal size")
+		}
+		size = len(share)
+	}
+	if size == 0 || size%2 != 0 {
+		return 0, fmt.Errorf("invalid leopard share size %d, must be even and non zero", size)
+	}
+	return size, nil
+}
+
+// leopardPack lays out each share in zero padded blocks, so that consecutive
+// pairs of bytes form a symbol. The padding only fills whole symbols, which
+// erasure to zero, so parity can be truncated to size by leopardUnpack. This
+// lets node hashes, which are not a multiple of a block, be erasured. Missing
+// shares stay nil.
+func leopardPack(shares [][]byte, size int) [][]byte {
+	padded := (size + leopardBlock - 1) / leopardBlock * leopardBlock
+	packed := make([][]byte, len(shares))
+	for i, share := range shares {
+		if share == nil {
+			continue
+		}
+		packed[i] = make([]byte, padded)
+		for t := 0; t < size/2; t++ {
+			lo, hi := leopardSymbol(t)
+			packed[i][lo], packed[i][hi] = share[2*t], share[2*t+1]
+		}
+	}
+	return packed
+}
+
+// leopardUnpack reverses leopardPack
+func leopardUnpack(shares [][]byte, size int) [][]byte {
+	unpacked := make([][]byte, len(shares))
+	for i, share := range shares {
+		unpacked[i] = make([]byte, size)
+		for t := 0; t < size/2; t++ {
+			lo, hi := leopardSymbol(t)
+			unpacked[i][2*t], unpacked[i][2*t+1] = share[lo], share[hi]
+		}
+	}
+	return unpacked
+}
+
+// leopardSymbol returns the positions of the low and high byte of symbol t
+func leopardSymbol(t int) (int, int) {
+	block, j := t/(leopardBlock/2), t%(leopardBlock/2)
+	return block*leopardBlock + j, block*leopardBlock + leopardBlock/2 + j
+}
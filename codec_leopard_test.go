@@ -0,0 +1,60 @@
+//go:build leopard
+
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/lazyledger/nmt/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeopardFF16(t *testing.T) {
+	// exceeds the 128 leaves supported by RSFG8
+	leafCount := 256
+	tree := NewNCMT(WithCodec(LeopardFF16{}), WithParallelism(4))
+	for i, d := range mockData(leafCount, 64) {
+		id := namespace.ID{0, 0, 0, 0, 0, 0, byte(i >> 8), byte(i)}
+		assert.NoError(t, tree.Push(namespace.NewPrefixedData(id.Size(), append(id, d.Data()...))))
+	}
+	root, err := tree.Build()
+	assert.NoError(t, err)
+
+	// decode the originals from the erasured leaves
+	samples, err := tree.Sample(rangePositions(uint(leafCount), uint(2*leafCount)))
+	assert.NoError(t, err)
+	opts := tree.Options()
+	repaired, err := Repair(root, &opts, samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, root, repaired.Root())
+}
+
+func TestLeopardFF16ShareSizes(t *testing.T) {
+	// node hashes are not a multiple of the 64 byte leopard block
+	input := [][]byte{make([]byte, 48), make([]byte, 48)}
+	input[0][0], input[1][47] = 1, 2
+	parity, err := LeopardFF16{}.Encode(input)
+	assert.NoError(t, err)
+	assert.Len(t, parity[0], 48)
+	decoded, err := LeopardFF16{}.Decode([][]byte{nil, nil, parity[0], parity[1]})
+	assert.NoError(t, err)
+	assert.Equal(t, append(input, parity...), decoded)
+
+	_, err = LeopardFF16{}.Encode([][]byte{{1, 2, 3}, {4, 5, 6}})
+	assert.Error(t, err)
+	_, err = LeopardFF16{}.Encode([][]byte{{1, 2}, {3, 4, 5, 6}})
+	assert.Error(t, err)
+}
+
+func TestLeopardFF16CodecCheck(t *testing.T) {
+	assert.NoError(t, CheckCodec(LeopardFF16{}))
+
+	tree := NewNCMT(WithCodec(LeopardFF16{}), WithCodecCheck())
+	for _, d := range mockData(16, 64) {
+		assert.NoError(t, tree.Push(d))
+	}
+	_, err := tree.Build()
+	assert.NoError(t, err)
+}
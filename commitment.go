@@ -0,0 +1,96 @@
+package ncmt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// CommitmentVersion identifies the scheme used to hash leaves and nodes
+type CommitmentVersion uint8
+
+const (
+	// CommitmentV0 hashes leaves as ns || hash(ns || data) and nodes as
+	// min || max || hash(childHash0 || childHashN...)
+	CommitmentV0 CommitmentVersion = iota
+	// CommitmentV1 prefixes leaf and node preimages with distinct domain
+	// separators, and length prefixes every hashed field, so that no leaf
+	// preimage can be interpreted as a node preimage or vice versa
+	CommitmentV1
+)
+
+const (
+	leafDomain byte = 0x00
+	nodeDomain byte = 0x01
+)
+
+// Validate returns an error if the version is not supported
+func (v CommitmentVersion) Validate() error {
+	if v > CommitmentV1 {
+		return fmt.Errorf("unsupported commitment version %d", v)
+	}
+	return nil
+}
+
+// Migrate rebuilds the original leaves of a built tree under version, with
+// every other option unchanged, so that the roots of existing trees can be
+// recomputed when a network upgrades, such as from CommitmentV0 to
+// CommitmentV1. The tree itself is unchanged, and keeps serving proofs against
+// its current root.
+func (n *NCMT) Migrate(version CommitmentVersion) (*NCMT, error) {
+	if len(n.layers) == 0 {
+		return nil, errors.New("tree must be built before migrating")
+	}
+	err := version.Validate()
+	if err != nil {
+		return nil, err
+	}
+	err = n.checkPayloads(0, n.originalWidth)
+	if err != nil {
+		return nil, fmt.Errorf("cannot migrate the tree: %s", err)
+	}
+	migrated := NewNCMT(func(o *Options) {
+		*o = *n.opts
+		o.CommitmentVersion = version
+	})
+	for _, lf := range n.originalLeaves() {
+		err = migrated.Push(NewNamespacedData(lf.data.NamespaceID(), lf.data.Data()))
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = migrated.Build()
+	if err != nil {
+		return nil, err
+	}
+	return migrated, nil
+}
+
+// hashScheme configures how leaves and nodes are hashed
+type hashScheme struct {
+	version      CommitmentVersion
+	commitCounts bool
+	salt         []byte
+}
+
+// scheme returns the hashScheme configured by the tree's options
+func (n *NCMT) scheme() hashScheme {
+	return hashScheme{
+		version:      n.opts.CommitmentVersion,
+		commitCounts: n.opts.LeafCounts,
+		salt:         n.opts.Salt,
+	}
+}
+
+// writeLenPrefixed writes the length of b as a big endian uint64 followed by b
+func writeLenPrefixed(h hash.Hash, b []byte) {
+	writeUint64(h, uint64(len(b)))
+	h.Write(b)
+}
+
+func writeUint64(h hash.Hash, v uint64) {
+	var encoded [8]byte
+	binary.BigEndian.PutUint64(encoded[:], v)
+	h.Write(encoded[:])
+}
@@ -0,0 +1,216 @@
+package ncmt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// CountProof shows how many original leaves belong to a namespace without
+// revealing them. It proves the nodes of a single layer that cover the
+// namespace, each of which has the namespace as its min or max, so that their
+// committed leaf counts add up to the number of leaves of the namespace.
+// Above the leaf layer, this requires committed leaf counts.
+type CountProof struct {
+	NamespaceID namespace.ID `cbor:"namespace_id"`
+	// Count is the number of original leaves of the namespace
+	Count uint64 `cbor:"count"`
+	// Layer is the layer of the covering nodes, where layer 0 is the leaf
+	// layer
+	Layer int `cbor:"layer"`
+	// Start and End are the range of covering nodes [Start, End) of the layer
+	Start uint `cbor:"start"`
+	End   uint `cbor:"end"`
+	// Width is the number of original leaves in the tree
+	Width uint `cbor:"width"`
+	// Children holds the hashes of the children of every covering node, in
+	// the order they are hashed, so that the nodes can be recomputed with
+	// their counts. At the leaf layer, it holds the hashes of the covering
+	// leaves instead.
+	Children [][]byte `cbor:"children"`
+	// NodeCounts holds the min and max leaf counts of every covering node. It
+	// is empty at the leaf layer.
+	NodeCounts [][2]uint64 `cbor:"node_counts"`
+	Set        [][]byte    `cbor:"set"`
+	Counts     [][2]uint64 `cbor:"counts"`
+}
+
+// ProveNamespaceCount creates a proof of the number of leaves of nID. If leaf
+// counts are committed, the covering nodes are taken from the highest layer
+// where each has nID as its min or max, so that the proof does not grow with
+// the number of leaves. Otherwise, the hash of every leaf of nID is included.
+// Absent namespaces have no count proof, use ProveNamespace instead.
+func (n *NCMT) ProveNamespaceCount(nID namespace.ID) (CountProof, error) {
+	if len(n.layers) == 0 {
+		return CountProof{}, errors.New("tree must be built before creating proofs")
+	}
+	if nID.Size() != n.opts.NamespaceSize {
+		return CountProof{}, fmt.Errorf(
+			"invalid namespace: expected size %d, received size %d",
+			n.opts.NamespaceSize,
+			nID.Size(),
+		)
+	}
+	found, start, end := n.foundInRange(nID)
+	if !found {
+		return CountProof{}, fmt.Errorf("namespace %x is not in the tree", []byte(nID))
+	}
+
+	batchSize := uint(n.opts.BatchSize / 2)
+	layer, span := 0, uint(1)
+	for n.opts.LeafCounts && layer < n.Depth() && n.coversAtEdges(layer+1, start, end, span*batchSize, nID) {
+		layer++
+		span *= batchSize
+	}
+	first, last := start/span, (end-1)/span+1
+
+	proof := CountProof{
+		NamespaceID: append(namespace.ID{}, nID...),
+		Count:       uint64(end - start),
+		Layer:       layer,
+		Start:       first,
+		End:         last,
+		Width:       n.originalWidth,
+	}
+	for pos := first; pos < last; pos++ {
+		if layer == 0 {
+			proof.Children = append(proof.Children, n.symbol(0, pos).hash)
+			continue
+		}
+		for _, child := range batchPositions(pos, n.levelWidth(layer-1), batchSize) {
+			proof.Children = append(proof.Children, n.symbol(layer-1, child).hash)
+		}
+		covering := n.symbol(layer, pos)
+		proof.NodeCounts = append(proof.NodeCounts, [2]uint64{covering.minCount, covering.maxCount})
+	}
+	plan := ProofPlan{Level: layer, Known: rangePositions(first, last)}
+	n.planSiblings(context.Background(), &plan)
+	proof.Set, proof.Counts = n.siblingHashes(plan.Siblings)
+	return proof, nil
+}
+
+// coversAtEdges returns true if every node of layer that covers the leaves
+// [start, end), where each node spans span leaves, has nID as its min or max
+func (n *NCMT) coversAtEdges(layer int, start, end, span uint, nID namespace.ID) bool {
+	for pos := start / span; pos <= (end-1)/span; pos++ {
+		covering := n.symbol(layer, pos)
+		if !covering.min.Equal(nID) && !covering.max.Equal(nID) {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyNamespaceCount checks that p is valid for root, showing that the tree
+// has exactly p.Count original leaves of p.NamespaceID. If opts is nil, the
+// default options are used.
+func VerifyNamespaceCount(root []byte, opts *Options, p CountProof) bool {
+	if opts == nil {
+		opts = NewNCMT().opts
+	}
+	return verifyCount(root, opts, p) == nil
+}
+
+// verifyCount returns a description of the first problem found with p
+func verifyCount(root []byte, opts *Options, p CountProof) error {
+	nsSize := int(opts.NamespaceSize)
+	err := checkBatchSize(opts.BatchSize)
+	if err != nil {
+		return err
+	}
+	err = opts.checkHash()
+	if err != nil {
+		return err
+	}
+	if len(p.NamespaceID) != nsSize {
+		return errors.New("invalid namespace size")
+	}
+	if p.Layer < 0 {
+		return fmt.Errorf("invalid layer %d", p.Layer)
+	}
+	if p.Layer > 0 && !opts.LeafCounts {
+		return errors.New("leaf counts must be committed to count above the leaf layer")
+	}
+	batchSize := uint(opts.BatchSize / 2)
+	width := p.Width
+	for i := 0; i < p.Layer; i++ {
+		if width%batchSize != 0 || width < batchSize {
+			return fmt.Errorf("layer %d is outside of a tree of width %d", p.Layer, p.Width)
+		}
+		width = width / batchSize
+	}
+	if p.Start >= p.End || p.End > width {
+		return fmt.Errorf("invalid range [%d, %d) of the %d nodes of layer %d", p.Start, p.End, width, p.Layer)
+	}
+	covering := p.End - p.Start
+	childCount := uint(1)
+	if p.Layer > 0 {
+		childCount = 2 * batchSize
+		if uint(len(p.NodeCounts)) != covering {
+			return fmt.Errorf("expected the counts of %d nodes, received %d", covering, len(p.NodeCounts))
+		}
+	}
+	if uint(len(p.Children)) != covering*childCount {
+		return fmt.Errorf("expected %d child hashes, received %d", covering*childCount, len(p.Children))
+	}
+
+	scheme := hashScheme{
+		version:      opts.CommitmentVersion,
+		commitCounts: opts.LeafCounts,
+		salt:         opts.Salt,
+	}
+	known := rangePositions(p.Start, p.End)
+	values := make(map[uint]node, len(known))
+	var count uint64
+	for i, pos := range known {
+		var value node
+		if p.Layer == 0 {
+			value, err = siblingNode(0, pos, width, p.Children[i], nsSize)
+			if err != nil {
+				return err
+			}
+		} else {
+			childWidth := width * batchSize
+			children := make([]node, 0, childCount)
+			for j, child := range batchPositions(pos, childWidth, batchSize) {
+				hash := p.Children[uint(i)*childCount+uint(j)]
+				childNode, err := siblingNode(p.Layer-1, child, childWidth, hash, nsSize)
+				if err != nil {
+					return err
+				}
+				children = append(children, childNode)
+			}
+			value = newNodeWithCounts(opts.FreshHash(), children, p.NodeCounts[i][0], p.NodeCounts[i][1], scheme)
+		}
+		// the counts of a node only cover its min and max namespace
+		switch {
+		case value.min.Equal(p.NamespaceID):
+			count += value.minCount
+		case value.max.Equal(p.NamespaceID):
+			count += value.maxCount
+		default:
+			return fmt.Errorf("node %d of layer %d does not have the namespace as its min or max", pos, p.Layer)
+		}
+		values[pos] = value
+	}
+	if count != p.Count {
+		return fmt.Errorf("the covering nodes hold %d leaves of the namespace, not %d", count, p.Count)
+	}
+
+	// no sibling outside of the covering nodes may hold the namespace
+	checkSibling := func(child, lowest, highest uint, sibling node) error {
+		if sibling.parity {
+			return nil
+		}
+		if child < lowest && !sibling.max.Less(p.NamespaceID) {
+			return errors.New("namespace found to the left of the covering nodes")
+		}
+		if child > highest && !p.NamespaceID.Less(sibling.min) {
+			return errors.New("namespace found to the right of the covering nodes")
+		}
+		return nil
+	}
+	return climb(root, opts, scheme, p.Layer, width, known, values, p.Set, p.Counts, checkSibling)
+}
@@ -0,0 +1,101 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/lazyledger/nmt/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+// countTree builds a tree of 64 leaves where namespace 4 covers the leaves
+// [5, 41), with namespace 2 before it and namespace 6 after it
+func countTree(t *testing.T, setters ...Option) *NCMT {
+	tree := NewNCMT(setters...)
+	for i, d := range mockData(64, 8) {
+		id := mockID(4)
+		switch {
+		case i < 5:
+			id = mockID(2)
+		case i >= 41:
+			id = mockID(6)
+		}
+		err := tree.Push(namespace.NewPrefixedData(id.Size(), append(id, d.Data()...)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := tree.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestProveNamespaceCount(t *testing.T) {
+	tree := countTree(t, WithLeafCounts())
+	root := tree.Root()
+	opts := tree.Options()
+
+	proof, err := tree.ProveNamespaceCount(mockID(4))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(36), proof.Count)
+	assert.Greater(t, proof.Layer, 0)
+	assert.Less(t, len(proof.Children), 36)
+	assert.True(t, VerifyNamespaceCount(root, &opts, proof))
+
+	// a namespace with fewer leaves than the batch size
+	small, err := tree.ProveNamespaceCount(mockID(2))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), small.Count)
+	assert.True(t, VerifyNamespaceCount(root, &opts, small))
+
+	// claiming a different count must fail
+	for _, count := range []uint64{35, 37, 0} {
+		wrong := proof
+		wrong.Count = count
+		assert.False(t, VerifyNamespaceCount(root, &opts, wrong), count)
+	}
+
+	// so must changing the counts of a covering node to match a wrong count
+	forged := proof
+	forged.Count = 37
+	forged.NodeCounts = append([][2]uint64{}, proof.NodeCounts...)
+	forged.NodeCounts[0][0]++
+	forged.NodeCounts[0][1]++
+	assert.False(t, VerifyNamespaceCount(root, &opts, forged))
+
+	// counts above the leaves are only bound if they are committed
+	assert.False(t, VerifyNamespaceCount(root, nil, proof))
+
+	// absent namespaces have no count proof
+	_, err = tree.ProveNamespaceCount(mockID(5))
+	assert.Error(t, err)
+}
+
+func TestProveNamespaceCountLeaves(t *testing.T) {
+	// without committed counts, every leaf of the namespace is included
+	tree := countTree(t)
+	root := tree.Root()
+
+	proof, err := tree.ProveNamespaceCount(mockID(4))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, proof.Layer)
+	assert.Len(t, proof.Children, 36)
+	assert.True(t, VerifyNamespaceCount(root, nil, proof))
+
+	wrong := proof
+	wrong.Count = 35
+	assert.False(t, VerifyNamespaceCount(root, nil, wrong))
+
+	// dropping a leaf of the namespace from either edge must fail
+	for _, shrunk := range []CountProof{
+		{Start: proof.Start + 1, End: proof.End, Children: proof.Children[1:]},
+		{Start: proof.Start, End: proof.End - 1, Children: proof.Children[:35]},
+	} {
+		shrunk.NamespaceID = proof.NamespaceID
+		shrunk.Count = 35
+		shrunk.Width = proof.Width
+		shrunk.Set = proof.Set
+		assert.False(t, VerifyNamespaceCount(root, nil, shrunk))
+	}
+}
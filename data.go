@@ -0,0 +1,74 @@
+package ncmt
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// NamespacedData is a minimal implementation of namespace.Data that can be
+// pushed directly to an NCMT
+type NamespacedData struct {
+	ID      namespace.ID
+	Payload []byte
+}
+
+// NewNamespacedData copies the id and payload into a new NamespacedData
+func NewNamespacedData(id namespace.ID, payload []byte) NamespacedData {
+	return NamespacedData{
+		ID:      append(namespace.ID{}, id...),
+		Payload: append([]byte{}, payload...),
+	}
+}
+
+// ParseNamespacedData splits raw namespace prefixed data into its id and
+// payload. The returned data shares memory with raw.
+func ParseNamespacedData(size namespace.IDSize, raw []byte) (NamespacedData, error) {
+	if size <= 0 {
+		return NamespacedData{}, fmt.Errorf("invalid namespace size %d", size)
+	}
+	if len(raw) < int(size) {
+		return NamespacedData{}, fmt.Errorf(
+			"data of length %d is shorter than the namespace size %d",
+			len(raw),
+			size,
+		)
+	}
+	return NamespacedData{
+		// limit the capacity of the id so that appending to it cannot overwrite
+		// the payload
+		ID:      namespace.ID(raw[:size:size]),
+		Payload: raw[size:],
+	}, nil
+}
+
+// NamespaceID fulfills the namespace.Data interface
+func (d NamespacedData) NamespaceID() namespace.ID {
+	return d.ID
+}
+
+// Data fulfills the namespace.Data interface
+func (d NamespacedData) Data() []byte {
+	return d.Payload
+}
+
+// Bytes returns the namespace prefixed data
+func (d NamespacedData) Bytes() []byte {
+	return append(append(make([]byte, 0, len(d.ID)+len(d.Payload)), d.ID...), d.Payload...)
+}
+
+// Validate checks that the data has a namespace of the expected size
+func (d NamespacedData) Validate(size namespace.IDSize) error {
+	if len(d.ID) == 0 {
+		return errors.New("missing namespace id")
+	}
+	if d.ID.Size() != size {
+		return fmt.Errorf(
+			"invalid namespace id: expected size %d, received size %d",
+			size,
+			d.ID.Size(),
+		)
+	}
+	return nil
+}
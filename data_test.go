@@ -0,0 +1,31 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespacedData(t *testing.T) {
+	raw := []byte{0, 0, 0, 0, 0, 0, 0, 1, 10, 11, 12}
+	data, err := ParseNamespacedData(8, raw)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 1}, []byte(data.NamespaceID()))
+	assert.Equal(t, []byte{10, 11, 12}, data.Data())
+	assert.Equal(t, raw, data.Bytes())
+	assert.NoError(t, data.Validate(8))
+	assert.Error(t, data.Validate(4))
+
+	// appending to the parsed id does not overwrite the payload
+	_ = append(data.ID, 99)
+	assert.Equal(t, []byte{10, 11, 12}, data.Data())
+
+	_, err = ParseNamespacedData(8, raw[:4])
+	assert.Error(t, err)
+
+	// parsed data can be pushed directly
+	tree := NewNCMT()
+	assert.NoError(t, tree.Push(data))
+	assert.Error(t, tree.Push(NewNamespacedData(mockID(0), []byte{1})))
+	assert.Error(t, NewNamespacedData(nil, []byte{1}).Validate(8))
+}
@@ -0,0 +1,206 @@
+package ncmt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// ErrDeadline is matched by every *DeadlineError
+var ErrDeadline = errors.New("proof deadline exceeded")
+
+// DeadlineError is returned when the context of a proof request ends before
+// the proof is complete. Plan holds the progress made so far, which can be
+// passed to ResumeProof instead of starting over.
+type DeadlineError struct {
+	Plan ProofPlan
+	// Err is the error of the context
+	Err error
+}
+
+func (e *DeadlineError) Error() string {
+	return fmt.Sprintf("%s after planning %d siblings: %s", ErrDeadline, len(e.Plan.Siblings), e.Err)
+}
+
+func (e *DeadlineError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DeadlineError) Is(target error) bool {
+	return target == ErrDeadline
+}
+
+// NodeCoordinate locates a node in the extended layer at Level, where level 0
+// is the leaf layer
+type NodeCoordinate struct {
+	Level    int  `cbor:"level"`
+	Position uint `cbor:"position"`
+}
+
+// ProofPlan is a partially planned proof of the leaves [Start, End). Every
+// layer below Level has been planned, and Known holds the positions at Level
+// that are computable from the proven leaves and Siblings.
+type ProofPlan struct {
+	Root        []byte           `cbor:"root"`
+	Start       uint             `cbor:"start"`
+	End         uint             `cbor:"end"`
+	NamespaceID namespace.ID     `cbor:"namespace_id"`
+	Siblings    []NodeCoordinate `cbor:"siblings"`
+	Level       int              `cbor:"level"`
+	Known       []uint           `cbor:"known"`
+}
+
+// ResumeProof finishes the proof planned by plan, which was returned in a
+// *DeadlineError by a proof request to this tree
+func (n *NCMT) ResumeProof(ctx context.Context, plan ProofPlan) (Proof, error) {
+	if len(n.layers) == 0 {
+		return Proof{}, errors.New("tree must be built before creating proofs")
+	}
+	if !bytes.Equal(plan.Root, n.Root()) {
+		return Proof{}, errors.New("proof plan belongs to a different tree")
+	}
+	err := n.checkPlan(plan)
+	if err != nil {
+		return Proof{}, fmt.Errorf("invalid proof plan: %s", err)
+	}
+	plan.Siblings = append([]NodeCoordinate{}, plan.Siblings...)
+	return n.planAndCollect(ctx, plan)
+}
+
+// newPlan starts a plan for the leaves [start, end). Assumes the range is
+// valid.
+func (n *NCMT) newPlan(start, end uint) ProofPlan {
+	return ProofPlan{
+		Root:  n.Root(),
+		Start: start,
+		End:   end,
+		Known: rangePositions(start, end),
+	}
+}
+
+// planAndCollect completes plan, returning a *DeadlineError if ctx ends first.
+// Every proven leaf must still have its payload.
+func (n *NCMT) planAndCollect(ctx context.Context, plan ProofPlan) (Proof, error) {
+	err := n.checkPayloads(plan.Start, plan.End)
+	if err != nil {
+		return Proof{}, err
+	}
+	err = n.planSiblings(ctx, &plan)
+	if err != nil {
+		return Proof{}, &DeadlineError{Plan: plan, Err: err}
+	}
+	return n.collect(plan, WithData), nil
+}
+
+// planSiblings plans the siblings of every remaining layer of plan. The
+// context is checked before each layer, so that plan is always left at the
+// boundary of a layer.
+func (n *NCMT) planSiblings(ctx context.Context, plan *ProofPlan) error {
+	batchSize := uint(n.opts.BatchSize / 2)
+	for width := n.levelWidth(plan.Level); width > 1; width = width / batchSize {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+		siblings, parents := planLevel(plan.Known, width, batchSize)
+		for _, sibling := range siblings {
+			plan.Siblings = append(plan.Siblings, NodeCoordinate{Level: plan.Level, Position: sibling})
+		}
+		plan.Known = parents
+		plan.Level++
+	}
+	return nil
+}
+
+// planLevel returns the siblings needed to compute the parents of the known
+// positions of an extended layer, in proof order, along with those parents
+func planLevel(known []uint, width, batchSize uint) ([]uint, []uint) {
+	var siblings []uint
+	parents := parentPositions(known, width, batchSize)
+	for _, parent := range parents {
+		for _, child := range batchPositions(parent, width, batchSize) {
+			if !containsPosition(known, child) {
+				siblings = append(siblings, child)
+			}
+		}
+	}
+	return siblings, parents
+}
+
+// collect creates the proof of a completed plan, holding either the data or
+// the hashes of the proven leaves depending on mode
+func (n *NCMT) collect(plan ProofPlan, mode ProofMode) Proof {
+	proof := Proof{
+		Start: plan.Start,
+		End:   plan.End,
+		Width: n.originalWidth,
+	}
+	for _, lf := range n.leaves[plan.Start:plan.End] {
+		if mode == HashesOnly {
+			proof.LeafHashes = append(proof.LeafHashes, lf.hash)
+			continue
+		}
+		proof.Data = append(proof.Data, NamespacedData{
+			ID:      lf.data.NamespaceID(),
+			Payload: lf.data.Data(),
+		}.Bytes())
+	}
+	proof.Set, proof.Counts = n.siblingHashes(plan.Siblings)
+	if len(plan.NamespaceID) != 0 {
+		proof.NamespaceID = append(namespace.ID{}, plan.NamespaceID...)
+	}
+	return proof
+}
+
+// siblingHashes collects the hashes of the siblings, and the leaf counts of
+// those that are original nodes above the leaves if counts are committed
+func (n *NCMT) siblingHashes(siblings []NodeCoordinate) ([][]byte, [][2]uint64) {
+	return n.appendSiblingHashes(nil, nil, siblings)
+}
+
+// appendSiblingHashes performs siblingHashes, appending to set and counts
+func (n *NCMT) appendSiblingHashes(set [][]byte, counts [][2]uint64, siblings []NodeCoordinate) ([][]byte, [][2]uint64) {
+	for _, coord := range siblings {
+		sibling := n.symbol(coord.Level, coord.Position)
+		set = append(set, sibling.hash)
+		if n.opts.LeafCounts && coord.Level > 0 && coord.Position < n.levelWidth(coord.Level) {
+			counts = append(counts, [2]uint64{sibling.minCount, sibling.maxCount})
+		}
+	}
+	return set, counts
+}
+
+// levelWidth is the number of original nodes in the layer at level
+func (n *NCMT) levelWidth(level int) uint {
+	width := n.originalWidth
+	for i := 0; i < level; i++ {
+		width = width / uint(n.opts.BatchSize/2)
+	}
+	return width
+}
+
+// checkPlan ensures that every coordinate of plan is within the tree, so that
+// a plan received from elsewhere cannot cause an out of range access
+func (n *NCMT) checkPlan(plan ProofPlan) error {
+	err := checkProofRange(plan.Start, plan.End, n.originalWidth)
+	if err != nil {
+		return err
+	}
+	if plan.Level < 0 || plan.Level > len(n.layers) {
+		return fmt.Errorf("level %d is outside of the tree", plan.Level)
+	}
+	for _, coord := range plan.Siblings {
+		if coord.Level < 0 || coord.Level >= plan.Level || coord.Position >= 2*n.levelWidth(coord.Level) {
+			return fmt.Errorf("sibling at level %d position %d is outside of the planned layers", coord.Level, coord.Position)
+		}
+	}
+	for _, pos := range plan.Known {
+		if pos >= 2*n.levelWidth(plan.Level) {
+			return fmt.Errorf("known position %d is outside of level %d", pos, plan.Level)
+		}
+	}
+	return nil
+}
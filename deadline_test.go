@@ -0,0 +1,71 @@
+package ncmt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countdownContext expires after its Err method has been called checks times
+type countdownContext struct {
+	context.Context
+	checks int
+}
+
+func (c *countdownContext) Err() error {
+	if c.checks <= 0 {
+		return context.DeadlineExceeded
+	}
+	c.checks--
+	return nil
+}
+
+func TestProofDeadline(t *testing.T) {
+	tree := sharedNamespaceTree(t, WithLeafCounts())
+	expected, err := tree.ProveRange(3, 6)
+	assert.NoError(t, err)
+
+	for checks := 0; checks < 2; checks++ {
+		ctx := &countdownContext{Context: context.Background(), checks: checks}
+		_, err := tree.ProveRangeContext(ctx, 3, 6)
+		assert.True(t, errors.Is(err, ErrDeadline))
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		var deadline *DeadlineError
+		assert.True(t, errors.As(err, &deadline))
+		assert.Equal(t, checks, deadline.Plan.Level)
+
+		// resuming from the plan produces the same proof
+		proof, err := tree.ResumeProof(context.Background(), deadline.Plan)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, proof)
+	}
+
+	expected, err = tree.ProveNamespace(mockID(4))
+	assert.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	_, err = tree.ProveNamespaceContext(ctx, mockID(4))
+	var deadline *DeadlineError
+	assert.True(t, errors.As(err, &deadline))
+	proof, err := tree.ResumeProof(context.Background(), deadline.Plan)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, proof)
+	opts := tree.Options()
+	assert.True(t, Verify(tree.Root(), &opts, proof))
+
+	// plans are only resumed by the tree they belong to
+	other := sharedNamespaceTree(t)
+	_, err = other.ResumeProof(context.Background(), deadline.Plan)
+	assert.Error(t, err)
+	plan := deadline.Plan
+	plan.Known = []uint{1000}
+	_, err = tree.ResumeProof(context.Background(), plan)
+	assert.Error(t, err)
+	plan = deadline.Plan
+	plan.Siblings = []NodeCoordinate{{Level: 5, Position: 0}}
+	_, err = tree.ResumeProof(context.Background(), plan)
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package ncmt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ExtendSymbols erasures a flat list of namespaced symbols, returning one parity
+// symbol per original. Each parity symbol is assigned the namespace of the
+// original at the same index, which is the same extension the NCMT applies to
+// its leaves, so it can be used independently of a tree.
+func ExtendSymbols(c Codec, symbols []NamespacedData) ([]NamespacedData, error) {
+	err := checkSymbols(c, symbols)
+	if err != nil {
+		return nil, err
+	}
+	raw := make([][]byte, len(symbols))
+	for i, symbol := range symbols {
+		raw[i] = symbol.Payload
+	}
+	encoded, err := c.Encode(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(encoded) != len(symbols) {
+		return nil, fmt.Errorf("codec %s returned %d parity symbols for %d originals", c.ID(), len(encoded), len(symbols))
+	}
+	parity := make([]NamespacedData, len(symbols))
+	for i, symbol := range symbols {
+		parity[i] = NamespacedData{
+			ID:      append(symbol.ID[:0:0], symbol.ID...),
+			Payload: encoded[i],
+		}
+	}
+	return parity, nil
+}
+
+// VerifyExtension checks that parity is exactly the extension of originals as
+// produced by ExtendSymbols
+func VerifyExtension(c Codec, originals, parity []NamespacedData) error {
+	if len(originals) != len(parity) {
+		return fmt.Errorf("invalid extension: %d originals and %d parity symbols", len(originals), len(parity))
+	}
+	expected, err := ExtendSymbols(c, originals)
+	if err != nil {
+		return err
+	}
+	for i := range expected {
+		if !expected[i].ID.Equal(parity[i].ID) {
+			return fmt.Errorf("invalid extension: parity symbol %d has the wrong namespace", i)
+		}
+		if !bytes.Equal(expected[i].Payload, parity[i].Payload) {
+			return fmt.Errorf("invalid extension: parity symbol %d does not match the encoded originals", i)
+		}
+	}
+	return nil
+}
+
+// checkSymbols ensures that symbols can be erasured by c
+func checkSymbols(c Codec, symbols []NamespacedData) error {
+	if len(symbols) == 0 {
+		return errors.New("no symbols to erasure")
+	}
+	if len(symbols) > c.MaxLeaves() {
+		return fmt.Errorf("codec %s can erasure at most %d symbols, received %d", c.ID(), c.MaxLeaves(), len(symbols))
+	}
+	size := len(symbols[0].Payload)
+	for i, symbol := range symbols {
+		if len(symbol.Payload) != size {
+			return fmt.Errorf("symbol %d has size %d, expected %d", i, len(symbol.Payload), size)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtendSymbols(t *testing.T) {
+	symbols := []NamespacedData{
+		{ID: []byte{0}, Payload: []byte{1}},
+		{ID: []byte{0}, Payload: []byte{2}},
+		{ID: []byte{1}, Payload: []byte{3}},
+		{ID: []byte{2}, Payload: []byte{4}},
+	}
+	parity, err := ExtendSymbols(RSFG8{}, symbols)
+	assert.NoError(t, err)
+	for i := range symbols {
+		assert.Equal(t, symbols[i].ID, parity[i].ID)
+	}
+	// matches the leaf extension in TestLeavesExtension
+	assert.Equal(t, []byte{135}, parity[0].Payload)
+	assert.Equal(t, []byte{191}, parity[3].Payload)
+	assert.NoError(t, VerifyExtension(RSFG8{}, symbols, parity))
+
+	// tampered parity is rejected
+	tampered := append([]NamespacedData{}, parity...)
+	tampered[1] = NamespacedData{ID: parity[1].ID, Payload: []byte{0}}
+	assert.Error(t, VerifyExtension(RSFG8{}, symbols, tampered))
+	tampered[1] = NamespacedData{ID: []byte{9}, Payload: parity[1].Payload}
+	assert.Error(t, VerifyExtension(RSFG8{}, symbols, tampered))
+	assert.Error(t, VerifyExtension(RSFG8{}, symbols, parity[1:]))
+
+	// uneven, empty, and oversized inputs are rejected
+	_, err = ExtendSymbols(RSFG8{}, append(symbols, NamespacedData{ID: []byte{3}, Payload: []byte{1, 2}}))
+	assert.Error(t, err)
+	_, err = ExtendSymbols(RSFG8{}, nil)
+	assert.Error(t, err)
+	_, err = ExtendSymbols(RSFG8{}, make([]NamespacedData, RSFG8{}.MaxLeaves()+1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package ncmt
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// BuildEvent is published to OnBuilt subscribers after every successful Build
+// or LoadExtended
+type BuildEvent struct {
+	Root  []byte
+	Stats BuildStats
+}
+
+// BuildStats summarizes a completed build
+type BuildStats struct {
+	OriginalWidth uint
+	Depth         int
+	Namespaces    int
+	// Duration is the time spent erasuring and hashing the tree
+	Duration time.Duration
+}
+
+// buildSubscribers holds the callbacks registered with OnBuilt
+type buildSubscribers struct {
+	mut  sync.Mutex
+	next int
+	fns  map[int]func(BuildEvent)
+}
+
+// OnBuilt calls fn after every successful Build or LoadExtended of the tree,
+// so that embedders can announce the root, start precomputing proofs, or
+// update metrics without polling. Callbacks run synchronously, in the order
+// they were registered, before Build returns. The returned function removes
+// the subscription.
+func (n *NCMT) OnBuilt(fn func(BuildEvent)) (unsubscribe func()) {
+	s := &n.onBuilt
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.fns == nil {
+		s.fns = make(map[int]func(BuildEvent))
+	}
+	id := s.next
+	s.next++
+	s.fns[id] = fn
+	return func() {
+		s.mut.Lock()
+		defer s.mut.Unlock()
+		delete(s.fns, id)
+	}
+}
+
+// publishBuilt calls every OnBuilt subscriber
+func (n *NCMT) publishBuilt(root []byte, duration time.Duration) {
+	s := &n.onBuilt
+	s.mut.Lock()
+	ids := make([]int, 0, len(s.fns))
+	for id := range s.fns {
+		ids = append(ids, id)
+	}
+	fns := make([]func(BuildEvent), 0, len(ids))
+	sort.Ints(ids)
+	for _, id := range ids {
+		fns = append(fns, s.fns[id])
+	}
+	s.mut.Unlock()
+
+	if len(fns) == 0 {
+		return
+	}
+	event := BuildEvent{
+		Root: root,
+		Stats: BuildStats{
+			OriginalWidth: n.originalWidth,
+			Depth:         n.Depth(),
+			Namespaces:    len(n.namespaceRanges),
+			Duration:      duration,
+		},
+	}
+	// subscribers are called without holding the lock, so that they can
+	// unsubscribe themselves
+	for _, fn := range fns {
+		fn(event)
+	}
+}
@@ -0,0 +1,77 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnBuilt(t *testing.T) {
+	tree := NewNCMT()
+	var order []int
+	var events []BuildEvent
+	tree.OnBuilt(func(e BuildEvent) {
+		order = append(order, 0)
+		events = append(events, e)
+	})
+	unsubscribe := tree.OnBuilt(func(BuildEvent) {
+		order = append(order, 1)
+	})
+	tree.OnBuilt(func(BuildEvent) {
+		order = append(order, 2)
+	})
+	// removed subscribers are no longer called
+	unsubscribe()
+
+	for _, d := range mockData(16, 8) {
+		err := tree.Push(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := tree.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 2}, order)
+	assert.Len(t, events, 1)
+	assert.Equal(t, root, events[0].Root)
+	assert.Equal(t, uint(16), events[0].Stats.OriginalWidth)
+	assert.Equal(t, tree.Depth(), events[0].Stats.Depth)
+	assert.Equal(t, 16, events[0].Stats.Namespaces)
+}
+
+func TestOnBuiltLoadExtended(t *testing.T) {
+	data := mockData(16, 8)
+	tree := NewNCMT()
+	for _, d := range data {
+		err := tree.Push(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	extended, err := tree.leaves.extend(tree.opts.Codec, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	originals := make([][]byte, len(data))
+	for i, d := range data {
+		originals[i] = append(append([]byte{}, d.NamespaceID()...), d.Data()...)
+	}
+	parity := extended.raw()
+
+	loaded := NewNCMT()
+	var roots [][]byte
+	loaded.OnBuilt(func(e BuildEvent) {
+		roots = append(roots, e.Root)
+	})
+	root, err := loaded.LoadExtended(originals, parity)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{root}, roots)
+
+	// failed loads are not published
+	rejected := NewNCMT()
+	rejected.OnBuilt(func(BuildEvent) {
+		t.Error("published a failed load")
+	})
+	_, err = rejected.LoadExtended(originals, parity[1:])
+	assert.Error(t, err)
+}
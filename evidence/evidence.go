@@ -0,0 +1,131 @@
+// Package evidence assembles evidence of misbehavior by the producer of an
+// NCMT into bundles with a single canonical encoding, so that they can be
+// submitted to, and checked by, a consensus layer. Only bad encoding proofs
+// prove misbehavior on their own. Failed samples and their timeouts report
+// withholding as observed by one node, which others must confirm by sampling
+// the same coordinates.
+package evidence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/evan-forbes/ncmt"
+)
+
+// Bundle is evidence against the tree described by Header
+type Bundle struct {
+	Header ncmt.TreeHeader `cbor:"header"`
+	// FailedSamples are sorted by layer, then by index, without duplicates
+	FailedSamples []FailedSample `cbor:"failed_samples"`
+	// BadEncoding, if set, proves that the tree was erasured incorrectly
+	BadEncoding *ncmt.BadEncodingProof `cbor:"bad_encoding,omitempty"`
+}
+
+// FailedSample is a symbol that could not be retrieved within Timeout
+type FailedSample struct {
+	Coordinate ncmt.Coordinate `cbor:"coordinate"`
+	Timeout    time.Duration   `cbor:"timeout"`
+}
+
+// AddFailedSample records that the symbol at c was not served within timeout.
+// A coordinate that was already recorded keeps its longest timeout.
+func (b *Bundle) AddFailedSample(c ncmt.Coordinate, timeout time.Duration) {
+	i := sort.Search(len(b.FailedSamples), func(i int) bool {
+		return !lessCoordinate(b.FailedSamples[i].Coordinate, c)
+	})
+	if i < len(b.FailedSamples) && b.FailedSamples[i].Coordinate == c {
+		if timeout > b.FailedSamples[i].Timeout {
+			b.FailedSamples[i].Timeout = timeout
+		}
+		return
+	}
+	b.FailedSamples = append(b.FailedSamples, FailedSample{})
+	copy(b.FailedSamples[i+1:], b.FailedSamples[i:])
+	b.FailedSamples[i] = FailedSample{Coordinate: c, Timeout: timeout}
+}
+
+// Verify checks that b is well formed for its header, and that its bad
+// encoding proof, if any, is valid. It returns true if b proves misbehavior on
+// its own, which is only the case if it holds a bad encoding proof. The
+// setters configure the codec, which is not part of the header, and must
+// produce a codec with the header's ID.
+func Verify(b Bundle, setters ...ncmt.Option) (bool, error) {
+	header := b.Header
+	err := header.Validate()
+	if err != nil {
+		return false, fmt.Errorf("invalid header: %s", err)
+	}
+	if header.OriginalWidth == 0 {
+		return false, errors.New("header does not describe the shape of the tree")
+	}
+	// copy the setters, so that appending does not write to the caller's array
+	setters = append(append([]ncmt.Option{}, setters...), header.ApplyTo())
+	opts := ncmt.NewNCMT(setters...).Options()
+	if opts.Codec.ID() != header.Codec {
+		return false, fmt.Errorf("header uses codec %s, configured with %s", header.Codec, opts.Codec.ID())
+	}
+	if len(b.FailedSamples) == 0 && b.BadEncoding == nil {
+		return false, errors.New("bundle holds no evidence")
+	}
+	for i, sample := range b.FailedSamples {
+		err := checkCoordinate(header, sample.Coordinate)
+		if err != nil {
+			return false, fmt.Errorf("invalid failed sample %d: %s", i, err)
+		}
+		if sample.Timeout <= 0 {
+			return false, fmt.Errorf("invalid failed sample %d: timeout %s", i, sample.Timeout)
+		}
+		if i > 0 && !lessCoordinate(b.FailedSamples[i-1].Coordinate, sample.Coordinate) {
+			return false, errors.New("failed samples are not sorted without duplicates")
+		}
+	}
+	if b.BadEncoding == nil {
+		return false, nil
+	}
+	if !ncmt.VerifyBadEncoding(header.Root, &opts, *b.BadEncoding) {
+		return false, errors.New("invalid bad encoding proof")
+	}
+	return true, nil
+}
+
+// checkCoordinate ensures that c is a symbol of a layer with parity in the
+// tree described by header
+func checkCoordinate(header ncmt.TreeHeader, c ncmt.Coordinate) error {
+	if c.Layer >= uint(header.Depth) {
+		return fmt.Errorf("layer %d has no parity, the tree has %d layers below its root", c.Layer, header.Depth)
+	}
+	width := header.OriginalWidth
+	for i := uint(0); i < c.Layer; i++ {
+		width = width / uint(header.BatchSize/2)
+	}
+	if c.Index >= 2*width {
+		return fmt.Errorf("index %d is outside of the %d symbols of layer %d", c.Index, 2*width, c.Layer)
+	}
+	return nil
+}
+
+func lessCoordinate(a, b ncmt.Coordinate) bool {
+	if a.Layer != b.Layer {
+		return a.Layer < b.Layer
+	}
+	return a.Index < b.Index
+}
+
+// Marshal encodes b canonically
+func Marshal(b Bundle) ([]byte, error) {
+	return ncmt.MarshalCanonical(b)
+}
+
+// Unmarshal decodes a bundle encoded by Marshal. Any other encoding of the
+// same bundle is rejected, so that every bundle has a single encoding.
+func Unmarshal(data []byte) (Bundle, error) {
+	var b Bundle
+	err := ncmt.UnmarshalCanonical(data, &b)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("failure to decode evidence: %s", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,137 @@
+package evidence
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/evan-forbes/ncmt"
+	"github.com/lazyledger/nmt/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+// badCodec corrupts the first parity symbol of every encoding
+type badCodec struct {
+	ncmt.RSFG8
+}
+
+func (c badCodec) Encode(input [][]byte) ([][]byte, error) {
+	encoded, err := c.RSFG8.Encode(input)
+	if err != nil {
+		return nil, err
+	}
+	encoded[0] = append([]byte{}, encoded[0]...)
+	encoded[0][0]++
+	return encoded, nil
+}
+
+func buildTree(t *testing.T, setters ...ncmt.Option) *ncmt.NCMT {
+	tree := ncmt.NewNCMT(setters...)
+	for i := 0; i < 16; i++ {
+		id := namespace.ID{0, 0, 0, 0, 0, 0, 0, byte(i)}
+		err := tree.Push(ncmt.NamespacedData{ID: id, Payload: []byte{byte(i), 1, 2, 3}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := tree.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestBundle(t *testing.T) {
+	tree := buildTree(t)
+	header, err := tree.Header()
+	assert.NoError(t, err)
+
+	b := Bundle{Header: header}
+	_, err = Verify(b)
+	assert.Error(t, err)
+	b.AddFailedSample(ncmt.Coordinate{Layer: 0, Index: 20}, time.Second)
+	b.AddFailedSample(ncmt.Coordinate{Layer: 1, Index: 3}, time.Second)
+	b.AddFailedSample(ncmt.Coordinate{Layer: 0, Index: 2}, time.Second)
+	b.AddFailedSample(ncmt.Coordinate{Layer: 0, Index: 2}, 2*time.Second)
+	assert.Equal(t, []FailedSample{
+		{ncmt.Coordinate{Layer: 0, Index: 2}, 2 * time.Second},
+		{ncmt.Coordinate{Layer: 0, Index: 20}, time.Second},
+		{ncmt.Coordinate{Layer: 1, Index: 3}, time.Second},
+	}, b.FailedSamples)
+
+	// failed samples alone do not prove misbehavior
+	proven, err := Verify(b)
+	assert.NoError(t, err)
+	assert.False(t, proven)
+
+	raw, err := Marshal(b)
+	assert.NoError(t, err)
+	decoded, err := Unmarshal(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, b, decoded)
+	_, err = Unmarshal(append(raw, 0))
+	assert.Error(t, err)
+
+	outside := b
+	outside.FailedSamples = []FailedSample{{ncmt.Coordinate{Layer: 1, Index: 16}, time.Second}}
+	_, err = Verify(outside)
+	assert.Error(t, err)
+	outside.FailedSamples = []FailedSample{{ncmt.Coordinate{Layer: 4, Index: 0}, time.Second}}
+	_, err = Verify(outside)
+	assert.Error(t, err)
+	unsorted := b
+	unsorted.FailedSamples = []FailedSample{b.FailedSamples[1], b.FailedSamples[0]}
+	_, err = Verify(unsorted)
+	assert.Error(t, err)
+	otherCodec := b
+	otherCodec.Header.Codec = "other"
+	_, err = Verify(otherCodec)
+	assert.Error(t, err)
+
+	// the setters of the caller are not written to
+	setters := make([]ncmt.Option, 0, 2)
+	_, err = Verify(b, setters...)
+	assert.NoError(t, err)
+	assert.Nil(t, setters[:1][0])
+}
+
+func TestBadEncodingBundle(t *testing.T) {
+	bad := buildTree(t, ncmt.WithCodec(badCodec{}))
+	header, err := bad.Header()
+	assert.NoError(t, err)
+	opts := ncmt.NewNCMT(header.ApplyTo()).Options()
+
+	samples, err := bad.Sample([]uint{16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31})
+	assert.NoError(t, err)
+	_, err = ncmt.Repair(header.Root, &opts, samples)
+	var badEncoding *ncmt.BadEncodingError
+	assert.True(t, errors.As(err, &badEncoding))
+
+	segment, err := bad.ProveSegment(1, 0)
+	assert.NoError(t, err)
+	err = ncmt.CheckSegment(header.Root, &opts, segment)
+	var badSegment *ncmt.BadEncodingError
+	assert.True(t, errors.As(err, &badSegment))
+
+	for _, proof := range []ncmt.BadEncodingProof{badEncoding.Proof, badSegment.Proof} {
+		proof := proof
+		b := Bundle{Header: header, BadEncoding: &proof}
+		proven, err := Verify(b)
+		assert.NoError(t, err)
+		assert.True(t, proven)
+
+		raw, err := Marshal(b)
+		assert.NoError(t, err)
+		decoded, err := Unmarshal(raw)
+		assert.NoError(t, err)
+		proven, err = Verify(decoded)
+		assert.NoError(t, err)
+		assert.True(t, proven)
+	}
+
+	// a bad encoding proof against an honest tree is rejected
+	honest, err := buildTree(t).Header()
+	assert.NoError(t, err)
+	_, err = Verify(Bundle{Header: honest, BadEncoding: &badSegment.Proof})
+	assert.Error(t, err)
+}
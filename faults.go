@@ -0,0 +1,124 @@
+package ncmt
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// FaultInjector decides when to inject faults into a Codec or a
+// TrustedRootStore. It exists to test the resilience of systems built on top
+// of NCMT, and should never be used in production.
+type FaultInjector interface {
+	// CorruptEncoding returns the encoded symbols to use in place of encoded.
+	// It must not modify encoded in place.
+	CorruptEncoding(encoded [][]byte) [][]byte
+	// DropWrite returns true if a write should be silently discarded
+	DropWrite() bool
+	// ReadDelay returns how long to wait before a read
+	ReadDelay() time.Duration
+}
+
+// WithFaultInjector wraps the codec so that every encoding passes through
+// f.CorruptEncoding. It must be passed after any Option that sets the codec.
+func WithFaultInjector(f FaultInjector) Option {
+	return func(o *Options) {
+		o.Codec = faultyCodec{Codec: o.Codec, faults: f}
+	}
+}
+
+// FaultyTrustStore wraps store so that writes may be dropped and reads may be
+// delayed according to f
+func FaultyTrustStore(store TrustedRootStore, f FaultInjector) TrustedRootStore {
+	return faultyStore{store: store, faults: f}
+}
+
+type faultyCodec struct {
+	Codec
+	faults FaultInjector
+}
+
+func (c faultyCodec) Encode(input [][]byte) ([][]byte, error) {
+	encoded, err := c.Codec.Encode(input)
+	if err != nil {
+		return nil, err
+	}
+	return c.faults.CorruptEncoding(encoded), nil
+}
+
+type faultyStore struct {
+	store  TrustedRootStore
+	faults FaultInjector
+}
+
+func (s faultyStore) Trusted(key []byte) bool {
+	if delay := s.faults.ReadDelay(); delay > 0 {
+		time.Sleep(delay)
+	}
+	return s.store.Trusted(key)
+}
+
+func (s faultyStore) Trust(key []byte) {
+	if s.faults.DropWrite() {
+		return
+	}
+	s.store.Trust(key)
+}
+
+// RandomFaults is a thread safe FaultInjector that injects each kind of fault
+// with a fixed probability. Rates of zero disable the respective fault.
+type RandomFaults struct {
+	// CorruptRate is the probability that each encoded symbol is corrupted
+	CorruptRate float64
+	// DropRate is the probability that a store write is dropped
+	DropRate float64
+	// DelayRate is the probability that a store read is delayed by Delay
+	DelayRate float64
+	Delay     time.Duration
+
+	mut sync.Mutex
+	rng *rand.Rand
+}
+
+// NewRandomFaults issues a RandomFaults with all faults disabled, using seed
+// so that runs can be reproduced
+func NewRandomFaults(seed int64) *RandomFaults {
+	return &RandomFaults{rng: rand.New(rand.NewSource(seed))}
+}
+
+// CorruptEncoding flips the first byte of each symbol selected by CorruptRate
+func (f *RandomFaults) CorruptEncoding(encoded [][]byte) [][]byte {
+	out := make([][]byte, len(encoded))
+	for i, symbol := range encoded {
+		out[i] = symbol
+		if len(symbol) == 0 || !f.roll(f.CorruptRate) {
+			continue
+		}
+		corrupted := append([]byte{}, symbol...)
+		corrupted[0] ^= 0xFF
+		out[i] = corrupted
+	}
+	return out
+}
+
+// DropWrite returns true with probability DropRate
+func (f *RandomFaults) DropWrite() bool {
+	return f.roll(f.DropRate)
+}
+
+// ReadDelay returns Delay with probability DelayRate
+func (f *RandomFaults) ReadDelay() time.Duration {
+	if f.roll(f.DelayRate) {
+		return f.Delay
+	}
+	return 0
+}
+
+func (f *RandomFaults) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.rng.Float64() < rate
+}
@@ -0,0 +1,72 @@
+package ncmt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultyCodec(t *testing.T) {
+	honest := sharedNamespaceTree(t)
+
+	// no faults leaves the tree untouched
+	clean := sharedNamespaceTree(t, WithFaultInjector(NewRandomFaults(1)))
+	samples, err := clean.Sample(rangePositions(16, 32))
+	assert.NoError(t, err)
+	_, err = Repair(clean.Root(), nil, samples)
+	assert.NoError(t, err)
+
+	faults := NewRandomFaults(1)
+	faults.CorruptRate = 1
+	corrupted := sharedNamespaceTree(t, WithFaultInjector(faults))
+	samples, err = corrupted.Sample(rangePositions(16, 32))
+	assert.NoError(t, err)
+	_, err = Repair(corrupted.Root(), nil, samples)
+	var badEncoding *BadEncodingError
+	assert.True(t, errors.As(err, &badEncoding))
+
+	// the codec is wrapped, not replaced
+	assert.Equal(t, honest.Options().Codec.ID(), corrupted.Options().Codec.ID())
+}
+
+func TestFaultyTrustStore(t *testing.T) {
+	faults := NewRandomFaults(1)
+	store := NewMemoryTrustStore(0, 0)
+	faulty := FaultyTrustStore(store, faults)
+
+	faults.DropRate = 1
+	faulty.Trust([]byte{1})
+	assert.False(t, faulty.Trusted([]byte{1}))
+	assert.Equal(t, 0, store.Len())
+
+	faults.DropRate = 0
+	faulty.Trust([]byte{1})
+	assert.True(t, faulty.Trusted([]byte{1}))
+
+	faults.DelayRate = 1
+	faults.Delay = 10 * time.Millisecond
+	start := time.Now()
+	assert.True(t, faulty.Trusted([]byte{1}))
+	assert.True(t, time.Since(start) >= faults.Delay)
+}
+
+func TestRandomFaultsReproducible(t *testing.T) {
+	symbols := make([][]byte, 64)
+	for i := range symbols {
+		symbols[i] = []byte{byte(i)}
+	}
+	run := func() [][]byte {
+		faults := NewRandomFaults(7)
+		faults.CorruptRate = 0.5
+		return faults.CorruptEncoding(symbols)
+	}
+	first := run()
+	assert.Equal(t, first, run())
+	assert.NotEqual(t, symbols, first)
+	// the input is never modified
+	for i, symbol := range symbols {
+		assert.Equal(t, []byte{byte(i)}, symbol)
+	}
+}
@@ -0,0 +1,97 @@
+package ncmt
+
+import (
+	"hash/fnv"
+	"math"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// NamespaceFilter is a bloom filter of the namespaces in a tree. A header can
+// carry one so that light clients can rule out namespaces without requesting
+// a proof. It is only as trustworthy as the header it is part of.
+type NamespaceFilter struct {
+	Bits   []byte `cbor:"bits"`
+	Hashes uint8  `cbor:"hashes"`
+}
+
+// WithNamespaceFilter includes a NamespaceFilter using bitsPerNamespace bits
+// for each namespace in the headers of the tree
+func WithNamespaceFilter(bitsPerNamespace int) Option {
+	return func(o *Options) {
+		o.NamespaceFilterBits = bitsPerNamespace
+	}
+}
+
+// NewNamespaceFilter creates a filter of ids using bitsPerNamespace bits for
+// each id, and the number of hashes that minimizes false positives
+func NewNamespaceFilter(ids []namespace.ID, bitsPerNamespace int) *NamespaceFilter {
+	size := (len(ids)*bitsPerNamespace + 7) / 8
+	if size == 0 {
+		size = 1
+	}
+	hashes := int(math.Round(float64(bitsPerNamespace) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	if hashes > 16 {
+		hashes = 16
+	}
+	f := &NamespaceFilter{Bits: make([]byte, size), Hashes: uint8(hashes)}
+	for _, id := range ids {
+		for _, bit := range f.positions(id) {
+			f.Bits[bit/8] |= 1 << (bit % 8)
+		}
+	}
+	return f
+}
+
+// MayContain returns false only if id is definitely not in the filter
+func (f *NamespaceFilter) MayContain(id namespace.ID) bool {
+	if len(f.Bits) == 0 {
+		return true
+	}
+	for _, bit := range f.positions(id) {
+		if f.Bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// positions derives the bits of id using double hashing
+func (f *NamespaceFilter) positions(id namespace.ID) []uint64 {
+	h1 := fnv.New64a()
+	h1.Write(id)
+	h2 := fnv.New64()
+	h2.Write(id)
+	a, b := h1.Sum64(), h2.Sum64()|1
+	bits := uint64(len(f.Bits)) * 8
+	positions := make([]uint64, f.Hashes)
+	for i := range positions {
+		positions[i] = (a + uint64(i)*b) % bits
+	}
+	return positions
+}
+
+// namespaceFilter creates the filter of a built tree's original leaves, or nil
+// if the tree is not configured to use one
+func (n *NCMT) namespaceFilter() *NamespaceFilter {
+	if n.opts.NamespaceFilterBits <= 0 {
+		return nil
+	}
+	var ids []namespace.ID
+	for i, lf := range n.originalLeaves() {
+		id := lf.data.NamespaceID()
+		if i == 0 || !id.Equal(ids[len(ids)-1]) {
+			ids = append(ids, id)
+		}
+	}
+	return NewNamespaceFilter(ids, n.opts.NamespaceFilterBits)
+}
+
+// ExcludesNamespace returns true if the header's namespace filter shows that
+// nID is not in the tree, in which case no absence proof needs to be requested
+func (h TreeHeader) ExcludesNamespace(nID namespace.ID) bool {
+	return h.NamespaceFilter != nil && !h.NamespaceFilter.MayContain(nID)
+}
@@ -0,0 +1,61 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/lazyledger/nmt/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaceFilter(t *testing.T) {
+	ids := mockIDs(64, 8)
+	filter := NewNamespaceFilter(ids, 10)
+	for _, id := range ids {
+		assert.True(t, filter.MayContain(id))
+	}
+	falsePositives := 0
+	for i := 0; i < 1000; i++ {
+		id := namespace.ID{1, 0, 0, 0, 0, 0, byte(i >> 8), byte(i)}
+		if filter.MayContain(id) {
+			falsePositives++
+		}
+	}
+	// around 1% are expected with 10 bits per namespace
+	assert.Less(t, falsePositives, 50)
+
+	assert.True(t, (&NamespaceFilter{}).MayContain(mockID(1)))
+}
+
+func TestVerifyWithNamespaceFilter(t *testing.T) {
+	tree := sharedNamespaceTree(t, WithNamespaceFilter(16))
+	header, err := tree.Header()
+	assert.NoError(t, err)
+	assert.NotNil(t, header.NamespaceFilter)
+
+	// namespaces 2, 4, ... 12 are in the tree
+	assert.False(t, header.ExcludesNamespace(mockID(4)))
+	proof, err := tree.ProveNamespace(mockID(4))
+	assert.NoError(t, err)
+	assert.True(t, VerifyWithHeader(header, proof))
+
+	assert.True(t, header.ExcludesNamespace(mockID(5)))
+	absence, err := tree.ProveNamespace(mockID(5))
+	assert.NoError(t, err)
+	assert.True(t, VerifyWithHeader(header, absence))
+
+	// a presence proof of an excluded namespace is inconsistent with the filter
+	relabeled := proof
+	relabeled.NamespaceID = mockID(5)
+	relabeled.Data = [][]byte{append(mockID(5), proof.Data[0][8:]...)}
+	assert.False(t, VerifyWithHeader(header, relabeled))
+
+	// without a filter, the absence proof is checked in full
+	header.NamespaceFilter = nil
+	assert.True(t, VerifyWithHeader(header, absence))
+	absence.Set[0] = absence.Set[1]
+	assert.False(t, VerifyWithHeader(header, absence))
+
+	header, err = sharedNamespaceTree(t).Header()
+	assert.NoError(t, err)
+	assert.Nil(t, header.NamespaceFilter)
+}
@@ -0,0 +1,65 @@
+package ncmt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+)
+
+// Fingerprint hashes the nodes of the top two layers of the tree, including
+// the erasures of the lower layer. It is far cheaper than CheckInvariants, and
+// detects any mutation of those layers, such as a memory corruption or a stray
+// write into a tree that is being served.
+func (n *NCMT) Fingerprint() ([]byte, error) {
+	if len(n.layers) == 0 {
+		return nil, errors.New("tree must be built before taking a fingerprint")
+	}
+	h := n.opts.FreshHash()
+	top := n.layers[len(n.layers)-1]
+	var lower []layer
+	if len(n.layers) > 1 {
+		lower = []layer{n.layers[len(n.layers)-2], n.extendedLayers[len(n.layers)-2]}
+	}
+	for _, l := range append([]layer{top}, lower...) {
+		writeUint64(h, uint64(len(l)))
+		for _, nd := range l {
+			writeLenPrefixed(h, nd.hash)
+			writeLenPrefixed(h, nd.min)
+			writeLenPrefixed(h, nd.max)
+		}
+	}
+	return h.Sum(nil), nil
+}
+
+// CheckFingerprint returns an error if the fingerprint of the tree differs from
+// the one captured at the end of the last Build
+func (n *NCMT) CheckFingerprint() error {
+	current, err := n.Fingerprint()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(current, n.fingerprint) {
+		return errors.New("tree fingerprint has changed since it was built")
+	}
+	return nil
+}
+
+// MonitorFingerprint calls CheckFingerprint every interval until ctx is done,
+// passing any error to alert. The tree must not be rebuilt while it is
+// monitored.
+func (n *NCMT) MonitorFingerprint(ctx context.Context, interval time.Duration, alert func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := n.CheckFingerprint()
+			if err != nil {
+				alert(err)
+			}
+		}
+	}
+}
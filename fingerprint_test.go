@@ -0,0 +1,55 @@
+package ncmt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	assert.NoError(t, tree.CheckFingerprint())
+
+	// mutate a node in the erasure of the second highest layer
+	extended := tree.extendedLayers[len(tree.layers)-2]
+	original := extended[0].hash
+	extended[0].hash = append([]byte{}, original...)
+	extended[0].hash[0]++
+	assert.Error(t, tree.CheckFingerprint())
+	extended[0].hash = original
+	assert.NoError(t, tree.CheckFingerprint())
+
+	_, err := NewNCMT().Fingerprint()
+	assert.Error(t, err)
+}
+
+func TestMonitorFingerprint(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	root := tree.layers[len(tree.layers)-1]
+	root[0].hash = append([]byte{}, root[0].hash...)
+	root[0].hash[0]++
+
+	alerts := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tree.MonitorFingerprint(ctx, time.Millisecond, func(err error) {
+			select {
+			case alerts <- err:
+			default:
+			}
+		})
+		close(done)
+	}()
+
+	select {
+	case err := <-alerts:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("no alert for a mutated tree")
+	}
+	cancel()
+	<-done
+}
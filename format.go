@@ -0,0 +1,189 @@
+package ncmt
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// Namespace IDs and roots are formatted as lower case hex with a 0x prefix
+// wherever they are shown, including errors and tracing attributes, so that
+// identifiers copy and paste cleanly between tools. User facing tools can use
+// bech32 instead, with the NamespaceHRP and RootHRP human readable parts.
+// Parsing accepts either format.
+
+const (
+	// NamespaceHRP is the human readable part of bech32 namespace IDs
+	NamespaceHRP = "ns"
+	// RootHRP is the human readable part of bech32 roots
+	RootHRP = "ncmt"
+)
+
+// FormatNamespace formats id as 0x prefixed hex
+func FormatNamespace(id namespace.ID) string {
+	return formatHex(id)
+}
+
+// FormatRoot formats root as 0x prefixed hex
+func FormatRoot(root []byte) string {
+	return formatHex(root)
+}
+
+// FormatNamespaceBech32 formats id as bech32 using NamespaceHRP
+func FormatNamespaceBech32(id namespace.ID) string {
+	return encodeBech32(NamespaceHRP, id)
+}
+
+// FormatRootBech32 formats root as bech32 using RootHRP
+func FormatRootBech32(root []byte) string {
+	return encodeBech32(RootHRP, root)
+}
+
+// ParseNamespace parses a namespace ID of the given size formatted by either
+// FormatNamespace or FormatNamespaceBech32
+func ParseNamespace(s string, size namespace.IDSize) (namespace.ID, error) {
+	raw, err := parseIdentifier(s, NamespaceHRP)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespace %q: %s", s, err)
+	}
+	if len(raw) != int(size) {
+		return nil, fmt.Errorf("invalid namespace %q: expected size %d, received size %d", s, size, len(raw))
+	}
+	return namespace.ID(raw), nil
+}
+
+// ParseRoot parses a root formatted by either FormatRoot or FormatRootBech32
+func ParseRoot(s string) ([]byte, error) {
+	raw, err := parseIdentifier(s, RootHRP)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root %q: %s", s, err)
+	}
+	return raw, nil
+}
+
+func formatHex(raw []byte) string {
+	return "0x" + hex.EncodeToString(raw)
+}
+
+// parseIdentifier decodes 0x prefixed hex, or bech32 with the expected human
+// readable part
+func parseIdentifier(s, hrp string) ([]byte, error) {
+	if strings.HasPrefix(s, "0x") {
+		return hex.DecodeString(s[2:])
+	}
+	decodedHRP, raw, err := decodeBech32(s)
+	if err != nil {
+		return nil, err
+	}
+	if decodedHRP != hrp {
+		return nil, fmt.Errorf("expected bech32 prefix %q, found %q", hrp, decodedHRP)
+	}
+	return raw, nil
+}
+
+/////////////////////////////////////////
+//  Bech32, as specified by BIP 173
+///////////////////////////////////////
+
+// The 90 character limit of BIP 173 is not enforced, as roots with large
+// namespaces exceed it.
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var bech32Generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+func bech32Polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= bech32Generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32ExpandHRP(hrp string) []byte {
+	expanded := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+func encodeBech32(hrp string, raw []byte) string {
+	data, _ := convertBits(raw, 8, 5, true)
+	values := append(bech32ExpandHRP(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ 1
+	for i := 0; i < 6; i++ {
+		data = append(data, byte(polymod>>uint(5*(5-i)))&31)
+	}
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, v := range data {
+		b.WriteByte(bech32Charset[v])
+	}
+	return b.String()
+}
+
+func decodeBech32(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, errors.New("bech32 string has mixed case")
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, errors.New("not 0x prefixed hex or bech32")
+	}
+	hrp := s[:sep]
+	data := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, fmt.Errorf("invalid bech32 character %q", s[i])
+		}
+		data = append(data, byte(v))
+	}
+	if bech32Polymod(append(bech32ExpandHRP(hrp), data...)) != 1 {
+		return "", nil, errors.New("invalid bech32 checksum")
+	}
+	raw, err := convertBits(data[:len(data)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, raw, nil
+}
+
+// convertBits regroups data from groups of from bits into groups of to bits
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	var acc, bits uint
+	maxv := uint(1)<<to - 1
+	out := make([]byte, 0, len(data)*int(from)/int(to)+1)
+	for _, v := range data {
+		acc = acc<<from | uint(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, errors.New("invalid bech32 padding")
+	}
+	return out, nil
+}
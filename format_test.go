@@ -0,0 +1,54 @@
+package ncmt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lazyledger/nmt/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	id := namespace.ID{0, 0, 0, 0, 0, 0, 1, 0xab}
+	assert.Equal(t, "0x00000000000001ab", FormatNamespace(id))
+	parsed, err := ParseNamespace("0x00000000000001ab", 8)
+	assert.NoError(t, err)
+	assert.Equal(t, id, parsed)
+
+	encoded := FormatNamespaceBech32(id)
+	assert.True(t, strings.HasPrefix(encoded, "ns1"))
+	parsed, err = ParseNamespace(encoded, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, id, parsed)
+	parsed, err = ParseNamespace(strings.ToUpper(encoded), 8)
+	assert.NoError(t, err)
+	assert.Equal(t, id, parsed)
+
+	tree := sharedNamespaceTree(t)
+	for _, s := range []string{FormatRoot(tree.Root()), FormatRootBech32(tree.Root())} {
+		root, err := ParseRoot(s)
+		assert.NoError(t, err)
+		assert.Equal(t, tree.Root(), root)
+	}
+
+	// BIP 173 test vector
+	hrp, raw, err := decodeBech32("A12UEL5L")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", hrp)
+	assert.Empty(t, raw)
+	assert.Equal(t, "a12uel5l", encodeBech32("a", nil))
+
+	corrupted := []byte(encoded)
+	corrupted[len(corrupted)-1] = bech32Charset[(strings.IndexByte(bech32Charset, corrupted[len(corrupted)-1])+1)%32]
+	for _, invalid := range []string{
+		"00000000000001ab",
+		"0x01ab",
+		"0xzz",
+		string(corrupted),
+		FormatRootBech32(id),
+		"ns1" + strings.ToUpper(encoded[3:5]) + encoded[5:],
+	} {
+		_, err := ParseNamespace(invalid, 8)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -1,10 +1,24 @@
 module github.com/evan-forbes/ncmt
 
-go 1.15
+go 1.18
 
 require (
+	github.com/fxamacker/cbor/v2 v2.4.0
 	github.com/lazyledger/go-leopard v0.0.0-20200604113236-298f93361181
 	github.com/lazyledger/nmt v0.0.0-20201112204856-4bc77a77815c
 	github.com/lazyledger/rsmt2d v0.0.0-20200922150919-822f4be6d768
-	github.com/stretchr/testify v1.5.1
+	github.com/stretchr/testify v1.8.2
+	go.opentelemetry.io/otel v1.14.0
+	go.opentelemetry.io/otel/trace v1.14.0
+)
+
+require (
+	github.com/NebulousLabs/merkletree v0.0.0-20181203152040-08d5d54b07f5 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/spacemonkeygo/errors v0.0.0-20171212215202-9064522e9fd1 // indirect
+	github.com/vivint/infectious v0.0.0-20190108171102-2455b059135b // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	gonum.org/v1/gonum v0.7.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
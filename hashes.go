@@ -0,0 +1,102 @@
+package ncmt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"sync"
+)
+
+// HashSHA256 identifies sha256, the default hash function
+const HashSHA256 = "sha256"
+
+// ErrUnknownHash is returned when no hash function has been registered for a
+// hash ID
+var ErrUnknownHash = errors.New("no hash function registered for hash")
+
+// hashFuncs maps hash IDs to hash functions, so that a single verifier can
+// check proofs from trees built with different hashes
+var hashFuncs = struct {
+	sync.RWMutex
+	fresh map[string]func() hash.Hash
+}{
+	fresh: map[string]func() hash.Hash{
+		HashSHA256: sha256.New,
+	},
+}
+
+// RegisterHash makes a hash function available to WithHash and to headers
+// that reference id
+func RegisterHash(id string, fresh func() hash.Hash) {
+	hashFuncs.Lock()
+	defer hashFuncs.Unlock()
+	hashFuncs.fresh[id] = fresh
+}
+
+// LookupHash returns the hash function registered for id
+func LookupHash(id string) (func() hash.Hash, error) {
+	hashFuncs.RLock()
+	defer hashFuncs.RUnlock()
+	fresh, found := hashFuncs.fresh[id]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownHash, id)
+	}
+	return fresh, nil
+}
+
+// WithHash sets the hash function to the one registered for id. If id is not
+// registered, pushing to, building, or verifying with the tree fails.
+func WithHash(id string) Option {
+	return func(o *Options) {
+		o.HashID = id
+		o.FreshHash, _ = LookupHash(id)
+	}
+}
+
+// checkHash ensures that a hash function is configured
+func (o *Options) checkHash() error {
+	if o.FreshHash == nil {
+		return fmt.Errorf("%w: %q", ErrUnknownHash, o.HashID)
+	}
+	return nil
+}
+
+// hashProbe is hashed to tell hash functions apart, as functions cannot be
+// compared directly
+var hashProbe = []byte("ncmt hash probe")
+
+// resolveHashID returns the registered ID of FreshHash, which can be set
+// without updating HashID. HashID is preferred if it matches.
+func (o *Options) resolveHashID() (string, error) {
+	err := o.checkHash()
+	if err != nil {
+		return "", err
+	}
+	digest := probeHash(o.FreshHash)
+
+	hashFuncs.RLock()
+	defer hashFuncs.RUnlock()
+	if fresh, found := hashFuncs.fresh[o.HashID]; found && bytes.Equal(probeHash(fresh), digest) {
+		return o.HashID, nil
+	}
+	ids := make([]string, 0, len(hashFuncs.fresh))
+	for id := range hashFuncs.fresh {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if bytes.Equal(probeHash(hashFuncs.fresh[id]), digest) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("%w: the configured hash function does not match any registered hash", ErrUnknownHash)
+}
+
+func probeHash(fresh func() hash.Hash) []byte {
+	h := fresh()
+	h.Write(hashProbe)
+	return h.Sum(nil)
+}
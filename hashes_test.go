@@ -0,0 +1,63 @@
+package ncmt
+
+import (
+	"crypto/sha512"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashFromHeader(t *testing.T) {
+	RegisterHash("sha512", sha512.New)
+	data := mockData(16, 8)
+	build := func(setters ...Option) *NCMT {
+		tree := NewNCMT(setters...)
+		for _, d := range data {
+			assert.NoError(t, tree.Push(d))
+		}
+		_, err := tree.Build()
+		assert.NoError(t, err)
+		return tree
+	}
+
+	for _, id := range []string{HashSHA256, "sha512"} {
+		tree := build(WithHash(id))
+		fresh, err := LookupHash(id)
+		assert.NoError(t, err)
+		assert.Len(t, tree.Root(), 2*8+fresh().Size())
+
+		// a verifier configured with the default hash uses the one in the header
+		header, err := tree.Header()
+		assert.NoError(t, err)
+		assert.Equal(t, id, header.Hash)
+		proof, err := tree.ProveNamespace(mockID(3))
+		assert.NoError(t, err)
+		assert.True(t, VerifyWithHeader(header, proof))
+	}
+
+	_, err := LookupHash("blake3")
+	assert.True(t, errors.Is(err, ErrUnknownHash))
+	tree := NewNCMT(WithHash("blake3"))
+	assert.Error(t, tree.Push(data[0]))
+
+	tree = build()
+	header, err := tree.Header()
+	assert.NoError(t, err)
+	proof, err := tree.ProveNamespace(mockID(3))
+	assert.NoError(t, err)
+	header.Hash = "blake3"
+	assert.False(t, VerifyWithHeader(header, proof))
+
+	// a hash function set without its ID is resolved from the registry
+	tree = build(func(o *Options) { o.FreshHash = sha512.New })
+	header, err = tree.Header()
+	assert.NoError(t, err)
+	assert.Equal(t, "sha512", header.Hash)
+	proof, err = tree.ProveNamespace(mockID(3))
+	assert.NoError(t, err)
+	assert.True(t, VerifyWithHeader(header, proof))
+	tree = build(func(o *Options) { o.FreshHash = sha512.New384 })
+	_, err = tree.Header()
+	assert.True(t, errors.Is(err, ErrUnknownHash))
+}
@@ -0,0 +1,108 @@
+package ncmt
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// TreeHeader summarizes a built tree: its root, along with every parameter a
+// verifier needs to interpret proofs against that root
+type TreeHeader struct {
+	Root              []byte            `cbor:"root"`
+	CommitmentVersion CommitmentVersion `cbor:"commitment_version"`
+	Codec             string            `cbor:"codec"`
+	BatchSize         int               `cbor:"batch_size"`
+	NamespaceSize     namespace.IDSize  `cbor:"namespace_size"`
+	LeafCounts        bool              `cbor:"leaf_counts"`
+	Salt              []byte            `cbor:"salt"`
+	// Hash is the ID of the hash function. An empty ID leaves the hash
+	// function of the options unchanged.
+	Hash string `cbor:"hash"`
+	// OriginalWidth, ExtendedWidth and Depth describe the shape of the tree.
+	// Headers created before they were added leave them all as 0.
+	OriginalWidth uint `cbor:"original_width"`
+	ExtendedWidth uint `cbor:"extended_width"`
+	Depth         int  `cbor:"depth"`
+	// NamespaceFilter optionally summarizes the namespaces of the tree
+	NamespaceFilter *NamespaceFilter `cbor:"namespace_filter"`
+	// SegmentSize is the size of the segments wide layers are erasured in, or
+	// 0 if every layer is erasured as a single codeword
+	SegmentSize int `cbor:"segment_size"`
+}
+
+// Header returns the TreeHeader of a built tree
+func (n *NCMT) Header() (TreeHeader, error) {
+	if len(n.layers) == 0 {
+		return TreeHeader{}, errors.New("tree must be built before creating a header")
+	}
+	hashID, err := n.opts.resolveHashID()
+	if err != nil {
+		return TreeHeader{}, err
+	}
+	return TreeHeader{
+		Root:              n.Root(),
+		CommitmentVersion: n.opts.CommitmentVersion,
+		Codec:             n.opts.Codec.ID(),
+		BatchSize:         n.opts.BatchSize,
+		NamespaceSize:     n.opts.NamespaceSize,
+		LeafCounts:        n.opts.LeafCounts,
+		Salt:              append([]byte{}, n.opts.Salt...),
+		Hash:              hashID,
+		OriginalWidth:     n.OriginalWidth(),
+		ExtendedWidth:     n.ExtendedWidth(),
+		Depth:             n.Depth(),
+		NamespaceFilter:   n.namespaceFilter(),
+		SegmentSize:       n.opts.SegmentSize,
+	}, nil
+}
+
+// Validate checks that the shape of the tree described by h is consistent
+// with its batch size
+func (h TreeHeader) Validate() error {
+	err := checkBatchSize(h.BatchSize)
+	if err != nil {
+		return err
+	}
+	if h.SegmentSize < 0 {
+		return fmt.Errorf("invalid segment size %d", h.SegmentSize)
+	}
+	if h.OriginalWidth == 0 && h.ExtendedWidth == 0 && h.Depth == 0 {
+		return nil
+	}
+	if h.ExtendedWidth != 2*h.OriginalWidth {
+		return fmt.Errorf("extended width %d is not twice the original width %d", h.ExtendedWidth, h.OriginalWidth)
+	}
+	// every layer batches BatchSize/2 original nodes into one
+	width := h.OriginalWidth
+	for depth := 0; depth < h.Depth; depth++ {
+		if h.SegmentSize > 0 && width > uint(h.SegmentSize) && width%uint(h.SegmentSize) != 0 {
+			return fmt.Errorf("layer of width %d cannot be divided into segments of %d", width, h.SegmentSize)
+		}
+		if width < 2 || width%uint(h.BatchSize/2) != 0 {
+			return fmt.Errorf("depth %d is inconsistent with original width %d", h.Depth, h.OriginalWidth)
+		}
+		width = width / uint(h.BatchSize/2)
+	}
+	if width != 1 {
+		return fmt.Errorf("depth %d is inconsistent with original width %d", h.Depth, h.OriginalWidth)
+	}
+	return nil
+}
+
+// ApplyTo returns an Option that configures a tree to use the commitment
+// parameters and hash function of the header. The codec is left unchanged.
+func (h TreeHeader) ApplyTo() Option {
+	return func(o *Options) {
+		if h.Hash != "" {
+			WithHash(h.Hash)(o)
+		}
+		o.CommitmentVersion = h.CommitmentVersion
+		o.BatchSize = h.BatchSize
+		o.NamespaceSize = h.NamespaceSize
+		o.LeafCounts = h.LeafCounts
+		o.Salt = append([]byte{}, h.Salt...)
+		o.SegmentSize = h.SegmentSize
+	}
+}
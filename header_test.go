@@ -0,0 +1,86 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSalt(t *testing.T) {
+	data := mockData(16, 8)
+	build := func(setters ...Option) *NCMT {
+		tree := NewNCMT(setters...)
+		for _, d := range data {
+			assert.NoError(t, tree.Push(d))
+		}
+		_, err := tree.Build()
+		assert.NoError(t, err)
+		return tree
+	}
+	for _, version := range []CommitmentVersion{CommitmentV0, CommitmentV1} {
+		unsalted := build(WithCommitmentVersion(version))
+		salted := build(WithCommitmentVersion(version), WithSalt([]byte("height-100")))
+		otherSalt := build(WithCommitmentVersion(version), WithSalt([]byte("height-101")))
+		assert.NotEqual(t, unsalted.Root(), salted.Root())
+		assert.NotEqual(t, salted.Root(), otherSalt.Root())
+
+		// the header carries the salt, so that a tree can be rebuilt from it
+		header, err := salted.Header()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("height-100"), header.Salt)
+		rebuilt := build(header.ApplyTo())
+		assert.Equal(t, salted.Root(), rebuilt.Root())
+	}
+
+	_, err := NewNCMT().Header()
+	assert.Error(t, err)
+}
+
+func TestHeaderShape(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	assert.Equal(t, uint(16), tree.OriginalWidth())
+	assert.Equal(t, uint(32), tree.ExtendedWidth())
+	assert.Equal(t, 4, tree.Depth())
+
+	header, err := tree.Header()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(16), header.OriginalWidth)
+	assert.Equal(t, uint(32), header.ExtendedWidth)
+	assert.Equal(t, 4, header.Depth)
+	assert.NoError(t, header.Validate())
+
+	proof, err := tree.ProveNamespace(mockID(4))
+	assert.NoError(t, err)
+	assert.True(t, VerifyWithHeader(header, proof))
+
+	inconsistent := header
+	inconsistent.ExtendedWidth = 16
+	assert.Error(t, inconsistent.Validate())
+	assert.False(t, VerifyWithHeader(inconsistent, proof))
+	inconsistent = header
+	inconsistent.Depth = 3
+	assert.Error(t, inconsistent.Validate())
+	inconsistent = header
+	inconsistent.OriginalWidth, inconsistent.ExtendedWidth = 12, 24
+	assert.Error(t, inconsistent.Validate())
+	// no tree can be built with these batch sizes, even without a shape
+	for _, batchSize := range []int{2, 6, 10, 12} {
+		inconsistent = header
+		inconsistent.BatchSize = batchSize
+		inconsistent.OriginalWidth, inconsistent.ExtendedWidth, inconsistent.Depth = 0, 0, 0
+		assert.Error(t, inconsistent.Validate(), batchSize)
+	}
+
+	// a proof from a tree of a different width is rejected
+	proof.Width = 8
+	assert.False(t, VerifyWithHeader(header, proof))
+
+	// headers without a shape are still accepted
+	legacy := header
+	legacy.OriginalWidth, legacy.ExtendedWidth, legacy.Depth = 0, 0, 0
+	assert.NoError(t, legacy.Validate())
+
+	unbuilt := NewNCMT()
+	assert.Equal(t, uint(0), unbuilt.OriginalWidth())
+	assert.Equal(t, 0, unbuilt.Depth())
+}
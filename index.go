@@ -0,0 +1,161 @@
+package ncmt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// A leaf index describes the original leaves of a tree whose data is kept in
+// external storage. It is a sequence of entries, sorted by namespace, each
+// encoded as
+// namespace || len(hash) || hash || length || offset
+// where the namespace is NamespaceSize bytes, every integer is a big endian
+// uint64, and [offset, offset+length) locates the leaf's data, without its
+// namespace, in the storage.
+
+// ImportLeafIndex pushes a leaf for every entry of the index read from r,
+// reading each leaf's data from storage. The hash of every entry is checked
+// against the leaf hash computed with the tree's options, so that an index
+// that does not match its storage is rejected. The tree must be empty, and is
+// left empty if importing fails.
+func (n *NCMT) ImportLeafIndex(r io.Reader, storage io.ReaderAt) error {
+	if len(n.leaves) != 0 {
+		return errors.New("cannot import a leaf index into a non empty tree")
+	}
+	err := n.opts.checkHash()
+	if err != nil {
+		return err
+	}
+	err = n.importLeafIndex(r, storage)
+	if err != nil {
+		// leave the tree empty, so that importing can be retried
+		n.clearLeaves()
+		return err
+	}
+	return nil
+}
+
+// importLeafIndex performs ImportLeafIndex on an empty tree
+func (n *NCMT) importLeafIndex(r io.Reader, storage io.ReaderAt) error {
+	br := bufio.NewReader(r)
+	for i := 0; ; i++ {
+		entry, err := readIndexEntry(br, n.opts.NamespaceSize)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid index entry %d: %s", i, err)
+		}
+		payload := make([]byte, entry.length)
+		_, err = storage.ReadAt(payload, int64(entry.offset))
+		if err != nil {
+			return fmt.Errorf("failure to read data of index entry %d: %s", i, err)
+		}
+		data := NamespacedData{ID: entry.id, Payload: payload}
+		if !bytes.Equal(newLeaf(n.opts.FreshHash(), data, n.scheme()).hash, entry.hash) {
+			return fmt.Errorf("index entry %d does not match the hash of its data", i)
+		}
+		err = n.Push(data)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// WriteLeafIndex writes a leaf index of the original leaves to w, with offsets
+// that assume the leaves' data is stored back to back in the same order
+func (n *NCMT) WriteLeafIndex(w io.Writer) error {
+	err := n.checkPayloads(0, uint(len(n.originalLeaves())))
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	var offset uint64
+	for _, lf := range n.originalLeaves() {
+		length := uint64(len(lf.data.Data()))
+		bw.Write(lf.data.NamespaceID())
+		writeIndexUint64(bw, uint64(len(lf.hash)))
+		bw.Write(lf.hash)
+		writeIndexUint64(bw, length)
+		writeIndexUint64(bw, offset)
+		offset += length
+	}
+	return bw.Flush()
+}
+
+type indexEntry struct {
+	id             namespace.ID
+	hash           []byte
+	length, offset uint64
+}
+
+// maxIndexHashSize and maxIndexDataSize bound the lengths of an entry, so that
+// a corrupted index cannot trigger a huge allocation
+const (
+	maxIndexHashSize = 1024
+	maxIndexDataSize = 1 << 30
+)
+
+// readIndexEntry reads a single entry, returning io.EOF only if r ends before
+// the entry begins
+func readIndexEntry(r io.Reader, nsSize namespace.IDSize) (indexEntry, error) {
+	id := make(namespace.ID, nsSize)
+	_, err := io.ReadFull(r, id)
+	if err != nil {
+		return indexEntry{}, err
+	}
+	hashSize, err := readIndexUint64(r)
+	if err != nil {
+		return indexEntry{}, err
+	}
+	if hashSize > maxIndexHashSize {
+		return indexEntry{}, fmt.Errorf("hash of %d bytes exceeds the limit of %d", hashSize, maxIndexHashSize)
+	}
+	hash := make([]byte, hashSize)
+	_, err = io.ReadFull(r, hash)
+	if err != nil {
+		return indexEntry{}, unexpectedEOF(err)
+	}
+	length, err := readIndexUint64(r)
+	if err != nil {
+		return indexEntry{}, err
+	}
+	if length > maxIndexDataSize {
+		return indexEntry{}, fmt.Errorf("data of %d bytes exceeds the limit of %d", length, maxIndexDataSize)
+	}
+	offset, err := readIndexUint64(r)
+	if err != nil {
+		return indexEntry{}, err
+	}
+	return indexEntry{id: id, hash: hash, length: length, offset: offset}, nil
+}
+
+func readIndexUint64(r io.Reader) (uint64, error) {
+	var encoded [8]byte
+	_, err := io.ReadFull(r, encoded[:])
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	return binary.BigEndian.Uint64(encoded[:]), nil
+}
+
+func writeIndexUint64(w io.Writer, v uint64) {
+	var encoded [8]byte
+	binary.BigEndian.PutUint64(encoded[:], v)
+	w.Write(encoded[:])
+}
+
+// unexpectedEOF converts io.EOF into io.ErrUnexpectedEOF for reads that
+// happen partway through an entry
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package ncmt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeafIndex(t *testing.T) {
+	for _, setters := range [][]Option{
+		nil,
+		{WithCommitmentVersion(CommitmentV1), WithSalt([]byte("salt"))},
+	} {
+		tree := sharedNamespaceTree(t, setters...)
+		index := &bytes.Buffer{}
+		assert.NoError(t, tree.WriteLeafIndex(index))
+		storage := &bytes.Buffer{}
+		for _, lf := range tree.originalLeaves() {
+			storage.Write(lf.data.Data())
+		}
+
+		imported := NewNCMT(setters...)
+		assert.NoError(t, imported.ImportLeafIndex(bytes.NewReader(index.Bytes()), bytes.NewReader(storage.Bytes())))
+		root, err := imported.Build()
+		assert.NoError(t, err)
+		assert.Equal(t, tree.Root(), root)
+
+		// storage that does not match the index, where the first leaves are
+		// still valid, leaves the tree empty so that importing can be retried
+		corrupted := append([]byte{}, storage.Bytes()...)
+		corrupted[len(corrupted)-1]++
+		retried := NewNCMT(setters...)
+		err = retried.ImportLeafIndex(bytes.NewReader(index.Bytes()), bytes.NewReader(corrupted))
+		assert.Error(t, err)
+		assert.Empty(t, retried.leaves)
+		assert.NoError(t, retried.ImportLeafIndex(bytes.NewReader(index.Bytes()), bytes.NewReader(storage.Bytes())))
+		root, err = retried.Build()
+		assert.NoError(t, err)
+		assert.Equal(t, tree.Root(), root)
+
+		// a truncated index
+		truncated := index.Bytes()[:index.Len()-3]
+		err = NewNCMT(setters...).ImportLeafIndex(bytes.NewReader(truncated), bytes.NewReader(storage.Bytes()))
+		assert.Error(t, err)
+
+		// options that do not match those used to hash the index
+		err = NewNCMT(WithSalt([]byte("other"))).ImportLeafIndex(bytes.NewReader(index.Bytes()), bytes.NewReader(storage.Bytes()))
+		assert.Error(t, err)
+
+		// the tree must be empty
+		err = imported.ImportLeafIndex(bytes.NewReader(index.Bytes()), bytes.NewReader(storage.Bytes()))
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,113 @@
+package ncmt
+
+import (
+	"fmt"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// CheckInvariants validates the internal structure of a tree, and is meant to
+// be called from integration tests to catch misuse of the API early. It checks
+// that the original leaves are sorted and consistent with the namespace ranges,
+// and, if the tree has been built, that each layer has the expected width and
+// number of erasures, that erasures use the reserved parity namespace, and
+// that namespace ranges only widen towards the root.
+func CheckInvariants(n *NCMT) error {
+	originals := n.originalLeaves()
+
+	// leaves are sorted and use a uniform namespace size
+	for i, lf := range originals {
+		if lf.data.NamespaceID().Size() != n.opts.NamespaceSize {
+			return fmt.Errorf("leaf %d has a namespace of size %d", i, lf.data.NamespaceID().Size())
+		}
+		if i > 0 && !originals[i-1].data.NamespaceID().LessOrEqual(lf.data.NamespaceID()) {
+			return fmt.Errorf("leaf %d is not sorted by namespace", i)
+		}
+	}
+
+	// every leaf is covered by exactly the range of its namespace
+	covered := uint(0)
+	for nsStr, rng := range n.namespaceRanges {
+		if rng.start >= rng.end || rng.end > uint(len(originals)) {
+			return fmt.Errorf("invalid range [%d, %d) for namespace %s", rng.start, rng.end, FormatNamespace(namespace.ID(nsStr)))
+		}
+		for i := rng.start; i < rng.end; i++ {
+			if string(originals[i].data.NamespaceID()) != nsStr {
+				return fmt.Errorf("leaf %d is outside of the range for its namespace", i)
+			}
+		}
+		covered += rng.end - rng.start
+	}
+	if covered != uint(len(originals)) {
+		return fmt.Errorf("namespace ranges cover %d of %d leaves", covered, len(originals))
+	}
+
+	if len(n.layers) == 0 {
+		return nil
+	}
+	return n.checkBuiltInvariants()
+}
+
+// checkBuiltInvariants validates the layers of a built tree
+func (n *NCMT) checkBuiltInvariants() error {
+	if uint(len(n.leaves)) != 2*n.originalWidth {
+		return fmt.Errorf(
+			"expected %d erasured leaves, found %d",
+			n.originalWidth,
+			uint(len(n.leaves))-n.originalWidth,
+		)
+	}
+	// erasured leaves share the namespace of their original
+	for i, lf := range n.leaves[n.originalWidth:] {
+		if !lf.min.Equal(n.leaves[i].min) || !lf.max.Equal(n.leaves[i].max) {
+			return fmt.Errorf("erasured leaf %d has a different namespace than its original", i)
+		}
+	}
+
+	batchSize := uint(n.opts.BatchSize / 2)
+	expectedWidth := n.originalWidth / batchSize
+	for i, l := range n.layers {
+		if uint(len(l)) != expectedWidth {
+			return fmt.Errorf("layer %d has width %d, expected %d", i, len(l), expectedWidth)
+		}
+		for j, nd := range l {
+			if !nd.min.LessOrEqual(nd.max) {
+				return fmt.Errorf("node %d of layer %d has a min greater than its max", j, i)
+			}
+			if j > 0 && !l[j-1].max.LessOrEqual(nd.min) {
+				return fmt.Errorf("node %d of layer %d is not sorted by namespace", j, i)
+			}
+		}
+		if i == len(n.layers)-1 {
+			break
+		}
+		// every layer except the root is erasured
+		if i >= len(n.extendedLayers) || len(n.extendedLayers[i]) != len(l) {
+			return fmt.Errorf("layer %d does not have an erasure for every node", i)
+		}
+		for j, parity := range n.extendedLayers[i] {
+			if !parity.parity {
+				return fmt.Errorf("erasured node %d of layer %d is not marked as parity", j, i)
+			}
+			if n.opts.UniformParityNamespace &&
+				(!parity.min.Equal(n.parityNamespace()) || !parity.max.Equal(n.parityNamespace())) {
+				return fmt.Errorf("erasured node %d of layer %d does not use the parity namespace", j, i)
+			}
+		}
+		// parents cover the namespace range of their children
+		for j, parent := range n.layers[i+1] {
+			children := l[uint(j)*batchSize : uint(j+1)*batchSize]
+			if !parent.min.Equal(children[0].min) || !children[len(children)-1].max.LessOrEqual(parent.max) {
+				return fmt.Errorf("node %d of layer %d does not cover the namespaces of its children", j, i+1)
+			}
+		}
+		expectedWidth = expectedWidth / batchSize
+	}
+	if len(n.extendedLayers) != len(n.layers)-1 {
+		return fmt.Errorf("expected %d erasured layers, found %d", len(n.layers)-1, len(n.extendedLayers))
+	}
+	if len(n.layers[len(n.layers)-1]) != 1 {
+		return fmt.Errorf("the last layer has %d nodes instead of a single root", len(n.layers[len(n.layers)-1]))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/lazyledger/nmt/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckInvariants(t *testing.T) {
+	// an empty tree, a tree with unbuilt leaves, and a built tree are valid
+	assert.NoError(t, CheckInvariants(NewNCMT()))
+	tree := NewNCMT()
+	for _, d := range mockData(16, 4) {
+		assert.NoError(t, tree.Push(d))
+	}
+	assert.NoError(t, CheckInvariants(tree))
+	_, err := tree.Build()
+	assert.NoError(t, err)
+	assert.NoError(t, CheckInvariants(tree))
+
+	// an inconsistent namespace range
+	broken := mockTree(16, 4, t)
+	broken.namespaceRanges[string(mockID(3))] = leafRange{start: 3, end: 5}
+	assert.Error(t, CheckInvariants(broken))
+
+	// unsorted leaves
+	broken = mockTree(16, 4, t)
+	broken.leaves[0], broken.leaves[1] = broken.leaves[1], broken.leaves[0]
+	assert.Error(t, CheckInvariants(broken))
+
+	// a missing erasured layer
+	broken = mockTree(16, 4, t)
+	broken.extendedLayers = broken.extendedLayers[1:]
+	assert.Error(t, CheckInvariants(broken))
+
+	// a node that does not cover its children
+	broken = mockTree(16, 4, t)
+	broken.layers[1][1].min = namespace.ID{0, 0, 0, 0, 0, 0, 0, 5}
+	assert.Error(t, CheckInvariants(broken))
+}
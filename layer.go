@@ -14,18 +14,20 @@ import (
 type layer []node
 
 // extend return a new layer of nodes that contain erasured data from the
-// original layer
-func (l layer) extend(c Codec) (layer, error) {
+// original layer, erasuring segments of segmentSize nodes independently using
+// up to workers goroutines
+func (l layer) extend(c Codec, segmentSize, workers int) (layer, error) {
 	extended := make([]node, len(l))
-	encodedData, err := c.Encode(l.raw())
+	encodedData, err := encodeSegments(c, l.raw(), segmentSize, workers)
 	if err != nil {
 		return nil, err
 	}
 	for i, n := range l {
 		cleanNode := node{
-			min:  n.min,
-			max:  n.max,
-			hash: encodedData[i],
+			min:    n.min,
+			max:    n.max,
+			hash:   encodedData[i],
+			parity: true,
 		}
 		extended[i] = cleanNode
 	}
@@ -44,67 +46,151 @@ func (l layer) raw() [][]byte {
 type node struct {
 	hash     []byte
 	min, max namespace.ID
+	// minCount and maxCount are the number of original leaves beneath the node
+	// that belong to the min and max namespace. Erasured nodes always have a
+	// count of zero.
+	minCount, maxCount uint64
+	// parity marks nodes that hold erasure data. Parity nodes are excluded
+	// when determining the namespace range of their parent.
+	parity bool
 }
 
 // newNode creates a new node using the hashes of the children nodes. Assumes
 // children have uniform height (coord.y), len(chilren) != 0, and children nodes
-// are presorted by namespace.ID from least to greatest. Uses the format
+// are presorted by namespace.ID from least to greatest. For CommitmentV0, uses the format
 // min ns(rawData) max ns(rawData) || hash(childHash0 || childHashN...) for the hash
-func newNode(h hash.Hash, children []node) node {
-	minID := children[0].min
-	maxID := children[len(children)-1].max
-	// use the position of the first child for
-	// gather the hashes of the children nodes
-	for _, child := range children {
-		h.Write(child.hash)
+// or, if counts are committed,
+// min ns(rawData) max ns(rawData) || hash(childHash0 || childHashN... || minCount || maxCount)
+// For CommitmentV1, uses the format
+// min ns(rawData) max ns(rawData) || hash(0x01 || len(min) || min || len(max) || max ||
+// len(children) || len(childHash0) || childHash0 ... [|| minCount || maxCount])
+func newNode(h hash.Hash, children []node, scheme hashScheme) node {
+	minID, maxID := namespaceRange(children)
+	minCount, maxCount := sumCounts(minID, maxID, children)
+	return newNodeWithCounts(h, children, minCount, maxCount, scheme)
+}
+
+// newNodeWithCounts creates a node like newNode, but with the given leaf
+// counts instead of those summed from the children, whose counts may not be
+// known
+func newNodeWithCounts(h hash.Hash, children []node, minCount, maxCount uint64, scheme hashScheme) node {
+	minID, maxID := namespaceRange(children)
+	switch scheme.version {
+	case CommitmentV1:
+		h.Write([]byte{nodeDomain})
+		writeLenPrefixed(h, minID)
+		writeLenPrefixed(h, maxID)
+		writeUint64(h, uint64(len(children)))
+		for _, child := range children {
+			writeLenPrefixed(h, child.hash)
+		}
+	default:
+		// use the position of the first child for
+		// gather the hashes of the children nodes
+		for _, child := range children {
+			h.Write(child.hash)
+		}
+	}
+	if scheme.commitCounts {
+		writeCounts(h, minCount, maxCount)
 	}
 	return node{
-		min: minID,
-		max: maxID,
+		min:      minID,
+		max:      maxID,
+		minCount: minCount,
+		maxCount: maxCount,
 		// include the min and max id's in the hash
-		hash: h.Sum(append(minID, maxID...)),
+		hash: h.Sum(append(append([]byte{}, minID...), maxID...)),
 	}
 }
 
 // nodeFromLeaves creates a new node using the hashes of the children leaves. Assumes
 // leaves have uniform height (coord.y), len(chilren) != 0, and children nodes
-// are presorted by namespace.ID from least to greatest. uses the format
-// min ns(rawData) max ns(rawData) || hash(leafHash0 || leafHashN...) for the hash
-func nodeFromLeaves(h hash.Hash, leaves []leaf) node {
-	minID := leaves[0].min
-	maxID := leaves[len(leaves)-1].max
-	// use the position of the first child for
-	// gather the hashes of the leaves nodes
-	for _, child := range leaves {
-		h.Write(child.hash)
+// are presorted by namespace.ID from least to greatest. uses the same format
+// as newNode for the hash
+func nodeFromLeaves(h hash.Hash, leaves []leaf, scheme hashScheme) node {
+	children := make([]node, len(leaves))
+	for i, lf := range leaves {
+		children[i] = lf.node
 	}
-	return node{
-		min:  minID,
-		max:  maxID,
-		hash: h.Sum(append(minID, maxID...)),
+	return newNode(h, children, scheme)
+}
+
+// namespaceRange returns the min namespace of the first original child and the
+// max namespace of the last original child, so that the reserved parity
+// namespace never widens the range of a parent. If every child is parity,
+// the range of the children is used as is.
+func namespaceRange(children []node) (namespace.ID, namespace.ID) {
+	first, last := -1, -1
+	for i, child := range children {
+		if child.parity {
+			continue
+		}
+		if first == -1 {
+			first = i
+		}
+		last = i
+	}
+	if first == -1 {
+		return children[0].min, children[len(children)-1].max
 	}
+	return children[first].min, children[last].max
+}
+
+// sumCounts totals the leaf counts of the min and max namespaces of children.
+// As children are sorted, a child can only hold leaves of the min namespace if
+// its own min is the min namespace, and the same goes for the max.
+func sumCounts(minID, maxID namespace.ID, children []node) (uint64, uint64) {
+	var minCount, maxCount uint64
+	for _, child := range children {
+		if child.min.Equal(minID) {
+			minCount += child.minCount
+		}
+		if child.max.Equal(maxID) {
+			maxCount += child.maxCount
+		}
+	}
+	return minCount, maxCount
+}
+
+// writeCounts writes the leaf counts to h as big endian uint64s
+func writeCounts(h hash.Hash, minCount, maxCount uint64) {
+	writeUint64(h, minCount)
+	writeUint64(h, maxCount)
 }
 
 type leaves []leaf
 
 // extend erasures the raw data in the leaves into a new set of leaves that has
-// the same namespace.ID prefixed as the original
-func (l leaves) extend(c Codec) (leaves, error) {
-	extended := make(leaves, len(l))
-	encodedLeaves, err := c.Encode(l.raw())
-	if err != nil {
-		return nil, err
-	}
+// the same namespace.ID prefixed as the original, erasuring segments of
+// segmentSize leaves independently using up to workers goroutines
+func (l leaves) extend(c Codec, segmentSize, workers int) (leaves, error) {
+	symbols := make([]NamespacedData, len(l))
 	for i, lf := range l {
-		id := make([]byte, lf.data.NamespaceID().Size())
-		copy(id, lf.data.NamespaceID())
-		newData := namespace.PrefixedDataFrom(id, encodedLeaves[i])
+		symbols[i] = NamespacedData{ID: lf.data.NamespaceID(), Payload: lf.data.Data()}
+	}
+	bounds := segments(len(symbols), segmentSize)
+	segmentParity := make([][]NamespacedData, len(bounds))
+	errs := make([]error, len(bounds))
+	forEachBatch(len(bounds), codecWorkers(c, workers), func(i int) {
+		segmentParity[i], errs[i] = ExtendSymbols(c, symbols[bounds[i][0]:bounds[i][1]])
+	})
+	parity := make([]NamespacedData, 0, len(symbols))
+	for i := range bounds {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		parity = append(parity, segmentParity[i]...)
+	}
+	extended := make(leaves, len(l))
+	for i, symbol := range parity {
 		newLeaf := leaf{
 			node: node{
-				min: id,
-				max: id,
+				min:    symbol.ID,
+				max:    symbol.ID,
+				parity: true,
 			},
-			data: newData,
+			data: symbol,
 		}
 		extended[i] = newLeaf
 	}
@@ -126,24 +212,43 @@ func (l leaves) raw() [][]byte {
 type leaf struct {
 	node
 	data namespace.Data
+	// pruned marks leaves whose payload has been dropped, keeping only their
+	// namespace and hash
+	pruned bool
 }
 
-// newLeaf creates a new leaf by hashing the data provided in the format
-// ns(rawData) || hash(leafPrefix || rawData)
-func newLeaf(h hash.Hash, data namespace.Data) leaf {
-	// hash the namespace id along with the
-	h.Write(append(data.NamespaceID(), data.Data()...))
+// newLeaf creates a new leaf by hashing the data provided. For CommitmentV0,
+// uses the format ns(rawData) || hash([salt ||] ns(rawData) || rawData), where
+// the salt is only included if it is not empty, and for CommitmentV1 uses the format
+// ns(rawData) || hash(0x00 || len(salt) || salt || len(ns) || ns(rawData) || len(rawData) || rawData)
+func newLeaf(h hash.Hash, data namespace.Data, scheme hashScheme) leaf {
+	// copy the id first, as it may share a backing array with the data
+	id := append([]byte{}, data.NamespaceID()...)
+	switch scheme.version {
+	case CommitmentV1:
+		h.Write([]byte{leafDomain})
+		writeLenPrefixed(h, scheme.salt)
+		writeLenPrefixed(h, id)
+		writeLenPrefixed(h, data.Data())
+	default:
+		// hash the namespace id along with the data
+		h.Write(scheme.salt)
+		h.Write(append(id, data.Data()...))
+	}
 	return leaf{
 		data: data,
 		node: node{
-			hash: h.Sum(data.NamespaceID()),
-			min:  data.NamespaceID(),
-			max:  data.NamespaceID(),
+			hash:     h.Sum(id[:len(id):len(id)]),
+			min:      data.NamespaceID(),
+			max:      data.NamespaceID(),
+			minCount: 1,
+			maxCount: 1,
 		},
 	}
 }
 
-// genParityNameSpaceIDs creates filler namespace.ID s for parity data
+// genParityNameSpaceIDs creates the reserved namespace.ID used for interior
+// parity nodes, which is the maximum namespace.ID of the given size
 func genParityNameSpaceID(size int8) namespace.ID {
 	var parityID namespace.ID
 	for i := int8(0); i < size; i++ {
@@ -0,0 +1,98 @@
+package ncmt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// ErrLayoutMismatch is matched by the errors of TranscodeProof for layouts that
+// do not produce the same tree from the same leaves
+var ErrLayoutMismatch = errors.New("layouts produce different trees")
+
+// LayoutParams are the parameters of a tree that determine how its leaves are
+// erasured and hashed into its root
+type LayoutParams struct {
+	CommitmentVersion CommitmentVersion `cbor:"commitment_version"`
+	Codec             string            `cbor:"codec"`
+	BatchSize         int               `cbor:"batch_size"`
+	NamespaceSize     namespace.IDSize  `cbor:"namespace_size"`
+	LeafCounts        bool              `cbor:"leaf_counts"`
+	Salt              []byte            `cbor:"salt"`
+	Hash              string            `cbor:"hash"`
+	SegmentSize       int               `cbor:"segment_size"`
+}
+
+// Layout returns the LayoutParams of the tree described by h
+func (h TreeHeader) Layout() LayoutParams {
+	return LayoutParams{
+		CommitmentVersion: h.CommitmentVersion,
+		Codec:             h.Codec,
+		BatchSize:         h.BatchSize,
+		NamespaceSize:     h.NamespaceSize,
+		LeafCounts:        h.LeafCounts,
+		Salt:              append([]byte{}, h.Salt...),
+		Hash:              h.Hash,
+		SegmentSize:       h.SegmentSize,
+	}
+}
+
+// TranscodeProof converts p, created for a tree with the from layout, into a
+// proof of the same leaves for the tree built from the same leaves with the to
+// layout, so that cached proofs remain usable across a layout upgrade. This is
+// only possible if both layouts produce the same tree for the width of p, in
+// which case p is returned unchanged. Layouts may only differ in their segment
+// size, and only if every layer of p is divided into the same segments, such
+// as when no layer is wider than either segment size. Otherwise the sibling
+// hashes of the new tree are not derivable from p, and an error matching
+// ErrLayoutMismatch is returned.
+func TranscodeProof(p Proof, from, to LayoutParams) (Proof, error) {
+	err := checkBatchSize(from.BatchSize)
+	if err != nil {
+		return Proof{}, err
+	}
+	sameSegments := from
+	sameSegments.SegmentSize = to.SegmentSize
+	if !sameSegments.equal(to) {
+		return Proof{}, fmt.Errorf("%w: only the segment size may differ", ErrLayoutMismatch)
+	}
+	if from.SegmentSize == to.SegmentSize {
+		return p, nil
+	}
+	batchSize := uint(from.BatchSize / 2)
+	for width := p.Width; width > 1; width = width / batchSize {
+		if width%batchSize != 0 {
+			return Proof{}, fmt.Errorf("width %d is not divisible by the batch size", width)
+		}
+		if !equalSegments(segments(int(width), from.SegmentSize), segments(int(width), to.SegmentSize)) {
+			return Proof{}, fmt.Errorf("%w: layer of width %d is erasured in different segments", ErrLayoutMismatch, width)
+		}
+	}
+	return p, nil
+}
+
+// equal returns true if l and other are identical
+func (l LayoutParams) equal(other LayoutParams) bool {
+	return l.CommitmentVersion == other.CommitmentVersion &&
+		l.Codec == other.Codec &&
+		l.BatchSize == other.BatchSize &&
+		l.NamespaceSize == other.NamespaceSize &&
+		l.LeafCounts == other.LeafCounts &&
+		bytes.Equal(l.Salt, other.Salt) &&
+		l.Hash == other.Hash &&
+		l.SegmentSize == other.SegmentSize
+}
+
+func equalSegments(a, b [][2]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package ncmt
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// layoutTrees builds the same count leaves with and without segments of 16
+func layoutTrees(t *testing.T, count int) (*NCMT, *NCMT) {
+	data := mockData(count, 8)
+	build := func(setters ...Option) *NCMT {
+		tree := NewNCMT(setters...)
+		for _, d := range data {
+			assert.NoError(t, tree.Push(d))
+		}
+		_, err := tree.Build()
+		assert.NoError(t, err)
+		return tree
+	}
+	return build(), build(WithSegmentSize(16))
+}
+
+func TestTranscodeProof(t *testing.T) {
+	// no layer of 16 leaves is wider than a segment, so both layouts produce
+	// the same tree
+	single, segmented := layoutTrees(t, 16)
+	from, err := single.Header()
+	assert.NoError(t, err)
+	to, err := segmented.Header()
+	assert.NoError(t, err)
+	proof, err := single.ProveRange(2, 5)
+	assert.NoError(t, err)
+	transcoded, err := TranscodeProof(proof, from.Layout(), to.Layout())
+	assert.NoError(t, err)
+	assert.True(t, VerifyWithHeader(to, transcoded))
+
+	// with 64 leaves, the leaf layer is erasured in different segments
+	single, segmented = layoutTrees(t, 64)
+	from, err = single.Header()
+	assert.NoError(t, err)
+	to, err = segmented.Header()
+	assert.NoError(t, err)
+	assert.NotEqual(t, from.Root, to.Root)
+	proof, err = single.ProveRange(2, 5)
+	assert.NoError(t, err)
+	_, err = TranscodeProof(proof, from.Layout(), to.Layout())
+	assert.True(t, errors.Is(err, ErrLayoutMismatch))
+
+	// any other change of layout changes the hashes of the tree
+	other := from.Layout()
+	other.LeafCounts = true
+	_, err = TranscodeProof(proof, from.Layout(), other)
+	assert.True(t, errors.Is(err, ErrLayoutMismatch))
+
+	// proofs are unchanged for identical layouts
+	transcoded, err = TranscodeProof(proof, from.Layout(), from.Layout())
+	assert.NoError(t, err)
+	assert.Equal(t, proof, transcoded)
+}
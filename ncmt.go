@@ -1,26 +1,109 @@
 package ncmt
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
 	"hash"
+	"time"
 
 	"github.com/lazyledger/nmt/namespace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Options configure a namespaced coded merkle tree
 type Options struct {
+	// UniformParityNamespace assigns the reserved parity namespace, the
+	// maximum namespace.ID, to the erasured nodes of every layer above the
+	// leaves. Erasured leaves always keep the namespace of their original.
 	UniformParityNamespace bool
 	BatchSize              int
 	NamespaceSize          namespace.IDSize
 	FreshHash              func() hash.Hash
+	HashID                 string
 	Codec                  Codec
+	// LeafCounts commits the number of original leaves of each node's min and
+	// max namespace into the node's hash
+	LeafCounts bool
+	// CheckCodec runs CheckCodec against the Codec before every Build
+	CheckCodec bool
+	// CommitmentVersion selects the scheme used to hash leaves and nodes
+	CommitmentVersion CommitmentVersion
+	// Salt is mixed into every leaf hash, which domain separates identical
+	// data committed to in different trees
+	Salt []byte
+	// Parallelism is the number of goroutines used to hash batches, and to
+	// erasure segments with a concurrent codec, during Build. Values less than
+	// 1 use one goroutine per available CPU.
+	Parallelism int
+	// Alignment controls how namespaces are aligned to batch boundaries
+	Alignment AlignmentMode
+	// Tracer records OpenTelemetry spans if it is set
+	Tracer trace.Tracer
+	// NamespaceFilterBits is the number of bits per namespace of the
+	// NamespaceFilter included in headers. Headers have no filter if it is 0.
+	NamespaceFilterBits int
+	// SegmentSize is the number of original nodes erasured together in layers
+	// wider than it. See WithSegmentSize.
+	SegmentSize int
 }
 
 // Option configures Options.
 type Option func(*Options)
 
+// WithCodec sets the codec used to erasure each layer
+func WithCodec(c Codec) Option {
+	return func(o *Options) {
+		o.Codec = c
+	}
+}
+
+// WithCodecCheck checks the codec against its stored test vector before every
+// Build. See CheckCodec.
+func WithCodecCheck() Option {
+	return func(o *Options) {
+		o.CheckCodec = true
+	}
+}
+
+// WithCommitmentVersion sets the scheme used to hash leaves and nodes
+func WithCommitmentVersion(v CommitmentVersion) Option {
+	return func(o *Options) {
+		o.CommitmentVersion = v
+	}
+}
+
+// WithSalt mixes a per tree salt, such as a block height or chain ID, into
+// every leaf hash
+func WithSalt(salt []byte) Option {
+	return func(o *Options) {
+		o.Salt = append([]byte{}, salt...)
+	}
+}
+
+// WithLeafCounts includes per namespace leaf counts in every node hash, so that
+// the number of leaves in a namespace can be proven using only the nodes that
+// cover it.
+func WithLeafCounts() Option {
+	return func(o *Options) {
+		o.LeafCounts = true
+	}
+}
+
+// WithParallelism sets the number of goroutines used to hash batches during
+// Build. Layers divided by WithSegmentSize are also erasured in parallel if the
+// codec is a ConcurrentCodec, while an unsegmented layer is a single codeword
+// and is always erasured by one goroutine. If n is less than 1,
+// runtime.GOMAXPROCS is used.
+func WithParallelism(n int) Option {
+	return func(o *Options) {
+		o.Parallelism = n
+	}
+}
+
 // NCMT creates and configures a namespaced coded merkle tree.
 type NCMT struct {
 	// keep extensions seperate for simplicity
@@ -30,6 +113,9 @@ type NCMT struct {
 	namespaceRanges map[string]leafRange
 
 	originalWidth uint
+	// fingerprint is captured at the end of each Build
+	fingerprint []byte
+	onBuilt     buildSubscribers
 	// options
 	opts *Options
 }
@@ -41,7 +127,9 @@ func NewNCMT(setters ...Option) *NCMT {
 		BatchSize:              4,
 		NamespaceSize:          namespace.IDSize(8),
 		FreshHash:              sha256.New,
+		HashID:                 HashSHA256,
 		Codec:                  RSFG8{},
+		Parallelism:            1,
 	}
 	for _, setter := range setters {
 		setter(defaultOpts)
@@ -74,6 +162,14 @@ func (n *NCMT) Root() []byte {
 // Push adds data to the leaves of the tree and updates the range. Throws error if data is not pushed
 // in order from the lowest (lexographical) id to the greatest
 func (n *NCMT) Push(data namespace.Data) error {
+	err := n.opts.CommitmentVersion.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid push: %s", err)
+	}
+	err = n.opts.checkHash()
+	if err != nil {
+		return fmt.Errorf("invalid push: %s", err)
+	}
 	// make sure that the id size is identical across the tree
 	if data.NamespaceID().Size() != n.opts.NamespaceSize {
 		return fmt.Errorf(
@@ -82,10 +178,23 @@ func (n *NCMT) Push(data namespace.Data) error {
 			data.NamespaceID(),
 		)
 	}
+	if n.opts.UniformParityNamespace && data.NamespaceID().Equal(n.parityNamespace()) {
+		return errors.New("invalid push: namespace.ID is reserved for parity data")
+	}
+	err = checkPadding(data)
+	if err != nil {
+		return fmt.Errorf("invalid push: %s", err)
+	}
+	if len(n.leaves) >= n.opts.maxLeaves() {
+		return fmt.Errorf(
+			"invalid push: codec %s supports at most %d leaves",
+			n.opts.Codec.ID(),
+			n.opts.Codec.MaxLeaves(),
+		)
+	}
 	if len(n.leaves) == 0 {
 		// add first leaf
-		n.leaves = append(n.leaves, newLeaf(n.opts.FreshHash(), data))
-		n.updateNamespaceRanges()
+		n.appendLeaf(data)
 		return nil
 	}
 
@@ -96,12 +205,22 @@ func (n *NCMT) Push(data namespace.Data) error {
 		return errors.New("invalid push: greater or equal namespace.ID required")
 	}
 
+	err = n.align(data.NamespaceID())
+	if err != nil {
+		return err
+	}
+
 	// add the data to existing leaves
-	n.leaves = append(n.leaves, newLeaf(n.opts.FreshHash(), data))
-	n.updateNamespaceRanges()
+	n.appendLeaf(data)
 	return nil
 }
 
+// appendLeaf hashes and adds data to the leaves without any validation
+func (n *NCMT) appendLeaf(data namespace.Data) {
+	n.leaves = append(n.leaves, newLeaf(n.opts.FreshHash(), data, n.scheme()))
+	n.updateNamespaceRanges()
+}
+
 func (n *NCMT) updateNamespaceRanges() {
 	if len(n.leaves) > 0 {
 		lastIndex := len(n.leaves) - 1
@@ -128,6 +247,67 @@ func (n *NCMT) foundInRange(nID namespace.ID) (bool, uint, uint) {
 	return found, foundRng.start, foundRng.end
 }
 
+// NamespaceRange returns the range of original leaves [start, end) that belong
+// to nID
+func (n *NCMT) NamespaceRange(nID namespace.ID) (start, end uint, found bool) {
+	found, start, end = n.foundInRange(nID)
+	return start, end, found
+}
+
+// Options returns a copy of the options used by the tree
+func (n *NCMT) Options() Options {
+	return *n.opts
+}
+
+// OriginalWidth returns the number of original leaves of a built tree, or 0 if
+// the tree has not been built
+func (n *NCMT) OriginalWidth() uint {
+	return n.originalWidth
+}
+
+// ExtendedWidth returns the number of leaves of a built tree, including their
+// erasures, or 0 if the tree has not been built
+func (n *NCMT) ExtendedWidth() uint {
+	return 2 * n.originalWidth
+}
+
+// Depth returns the number of layers above the leaves of a built tree, the
+// last of which holds the root, or 0 if the tree has not been built
+func (n *NCMT) Depth() int {
+	return len(n.layers)
+}
+
+// parityNamespace returns the reserved namespace for interior parity nodes
+func (n *NCMT) parityNamespace() namespace.ID {
+	return genParityNameSpaceID(int8(n.opts.NamespaceSize))
+}
+
+// Get returns the original data pushed at idx
+func (n *NCMT) Get(idx uint) (namespace.Data, error) {
+	originals := n.originalLeaves()
+	if idx >= uint(len(originals)) {
+		return nil, fmt.Errorf(
+			"leaf out of range: max range %d, index given %d",
+			len(originals),
+			idx,
+		)
+	}
+	err := n.checkPayloads(idx, idx+1)
+	if err != nil {
+		return nil, err
+	}
+	return originals[idx].data, nil
+}
+
+// originalLeaves returns only the pushed leaves, excluding any erasured leaves
+// added during Build
+func (n *NCMT) originalLeaves() leaves {
+	if len(n.layers) == 0 {
+		return n.leaves
+	}
+	return n.leaves[:n.originalWidth]
+}
+
 // A leafRange represents the contiguous set of leaves [Start,End).
 type leafRange struct {
 	start uint
@@ -142,78 +322,304 @@ type leafRange struct {
 // the root hash of the tree is generated. Build overides any data cached from a
 // previous Build
 func (n *NCMT) Build() ([]byte, error) {
-	n.originalWidth = uint(len(n.leaves))
+	return n.BuildContext(context.Background())
+}
 
+// BuildContext performs Build, creating any tracing spans as children of ctx
+func (n *NCMT) BuildContext(ctx context.Context) (root []byte, err error) {
+	start := time.Now()
+	ctx, span := startSpan(ctx, n.opts.Tracer, "ncmt.Build", attribute.Int("ncmt.leaves", len(n.leaves)))
+	defer func() { endSpan(span, err) }()
+	err = n.checkBuildable()
+	if err != nil {
+		return nil, err
+	}
+	extendedLeaves, err := n.extendLeaves(ctx)
+	if err != nil {
+		return nil, err
+	}
+	root, err = n.build(ctx, extendedLeaves)
+	if err != nil {
+		return nil, err
+	}
+	n.publishBuilt(root, time.Since(start))
+	return root, nil
+}
+
+// LoadExtended pushes namespace prefixed originals, checks that parity is the
+// erasure of their data, and builds the tree. Nodes that receive an already
+// extended dataset can use this instead of checking the parity and then calling
+// Build, which would erasure the leaves a second time. The tree must be empty,
+// and is left empty if loading fails.
+func (n *NCMT) LoadExtended(originals, parity [][]byte) ([]byte, error) {
+	start := time.Now()
+	if len(n.leaves) != 0 {
+		return nil, errors.New("cannot load extended data into a non empty tree")
+	}
+	root, err := n.loadExtended(originals, parity)
+	if err != nil {
+		// leave the tree empty, so that loading can be retried
+		n.clearLeaves()
+		return nil, err
+	}
+	n.publishBuilt(root, time.Since(start))
+	return root, nil
+}
+
+// loadExtended performs LoadExtended on an empty tree
+func (n *NCMT) loadExtended(originals, parity [][]byte) ([]byte, error) {
+	if len(originals) != len(parity) {
+		return nil, fmt.Errorf(
+			"invalid extended data: %d originals and %d parity",
+			len(originals),
+			len(parity),
+		)
+	}
+	for i, raw := range originals {
+		data, err := ParseNamespacedData(n.opts.NamespaceSize, raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid original %d: %s", i, err)
+		}
+		err = n.Push(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := n.checkBuildable()
+	if err != nil {
+		return nil, err
+	}
+
+	extendedLeaves, err := n.extendLeaves(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	for i, lf := range extendedLeaves {
+		if !bytes.Equal(lf.data.Data(), parity[i]) {
+			return nil, fmt.Errorf("invalid extended data: parity %d does not match the encoded originals", i)
+		}
+	}
+	return n.build(context.Background(), extendedLeaves)
+}
+
+// clearLeaves removes every pushed leaf, along with anything built from them
+func (n *NCMT) clearLeaves() {
+	n.leaves = nil
+	n.namespaceRanges = make(map[string]leafRange)
+	n.layers = nil
+	n.extendedLayers = nil
+	n.originalWidth = 0
+	n.fingerprint = nil
+}
+
+// Validate checks, without building, everything that Build would reject, so
+// that problems can be found while leaves are still being pushed. The order,
+// namespaces and sizes of the pushed leaves are rechecked, in case their data
+// was modified after being pushed.
+func (n *NCMT) Validate() error {
+	if len(n.layers) != 0 {
+		return errors.New("tree has already been built")
+	}
+	err := n.checkBuildable()
+	if err != nil {
+		return err
+	}
+	symbols := make([]NamespacedData, len(n.leaves))
+	for i, lf := range n.leaves {
+		id := lf.data.NamespaceID()
+		if id.Size() != n.opts.NamespaceSize {
+			return fmt.Errorf("leaf %d has a namespace of size %d, expected %d", i, id.Size(), n.opts.NamespaceSize)
+		}
+		if n.opts.UniformParityNamespace && id.Equal(n.parityNamespace()) {
+			return fmt.Errorf("leaf %d uses the namespace reserved for parity data", i)
+		}
+		err = checkPadding(lf.data)
+		if err != nil {
+			return fmt.Errorf("leaf %d: %s", i, err)
+		}
+		if i > 0 && id.Less(n.leaves[i-1].data.NamespaceID()) {
+			return fmt.Errorf("leaf %d is out of namespace order", i)
+		}
+		symbols[i] = NamespacedData{ID: id, Payload: lf.data.Data()}
+	}
+	for _, bounds := range segments(len(symbols), n.opts.SegmentSize) {
+		err = checkSymbols(n.opts.Codec, symbols[bounds[0]:bounds[1]])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkBatchSize ensures that batchSize is a power of two that batches at
+// least two original nodes into each parent. Leaf counts are powers of half the
+// batch size that are also multiples of it, which no count satisfies when that
+// half is odd, as with a batch size of 6.
+func checkBatchSize(batchSize int) error {
+	if batchSize < 4 || batchSize&(batchSize-1) != 0 {
+		return fmt.Errorf("invalid batch size %d", batchSize)
+	}
+	return nil
+}
+
+// checkBuildable ensures that the pushed leaves can be batched and erasured
+func (n *NCMT) checkBuildable() error {
+	err := n.opts.checkHash()
+	if err != nil {
+		return err
+	}
+	err = checkBatchSize(n.opts.BatchSize)
+	if err != nil {
+		return err
+	}
 	// make sure that there will not be any left over leaves
 	if len(n.leaves)%n.opts.BatchSize != 0 {
-		return nil, errors.New("numbers of leaves must be divisible by the batch size")
+		return errors.New("numbers of leaves must be divisible by the batch size")
 	}
-	// erasure leaves and create the first layer
-	err := n.consolidateLeaves()
+	// every layer must also batch evenly into the next, up to the root
+	batchSize := n.opts.BatchSize / 2
+	for width := len(n.leaves); width > 1; width = width / batchSize {
+		if width%batchSize != 0 {
+			return fmt.Errorf(
+				"%d leaves cannot be batched into a single root by batches of %d",
+				len(n.leaves),
+				batchSize,
+			)
+		}
+	}
+	if len(n.leaves) > n.opts.maxLeaves() {
+		return fmt.Errorf(
+			"codec %s supports at most %d leaves, tree has %d",
+			n.opts.Codec.ID(),
+			n.opts.Codec.MaxLeaves(),
+			len(n.leaves),
+		)
+	}
+	err = n.opts.checkSegments(len(n.leaves))
+	if err != nil {
+		return err
+	}
+	err = n.opts.CommitmentVersion.Validate()
+	if err != nil {
+		return err
+	}
+	if n.opts.CheckCodec {
+		return CheckCodec(n.opts.Codec)
+	}
+	return nil
+}
+
+// extendLeaves erasures the leaf data, and hashes each erasured leaf in the
+// same way as the originals so that they are committed to by the tree
+func (n *NCMT) extendLeaves(ctx context.Context) (leaves, error) {
+	_, span := n.startEncodeSpan(ctx, 0)
+	encodeTimer := startProfile(encodeStage, 0)
+	extended, err := n.leaves.extend(n.opts.Codec, n.opts.SegmentSize, n.opts.Parallelism)
+	encodeTimer.stop()
+	endSpan(span, err)
 	if err != nil {
 		return nil, err
 	}
+	forEachBatch(len(extended), n.opts.Parallelism, func(i int) {
+		extended[i].hash = newLeaf(n.opts.FreshHash(), extended[i].data, n.scheme()).hash
+	})
+	return extended, nil
+}
+
+// build creates every layer of the tree using the erasures of the leaves
+func (n *NCMT) build(ctx context.Context, extendedLeaves leaves) ([]byte, error) {
+	n.originalWidth = uint(len(n.leaves))
+
+	// create the first layer
+	n.consolidateLeaves(extendedLeaves)
 
 	// keep consolidating nodes until the root is calculated
 	for len(n.layers[len(n.layers)-1]) > 1 {
-		nextLayer, err := n.consolidateNodes()
+		nextLayer, err := n.consolidateNodes(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failure to create new layer: %s", err)
 		}
 		n.layers = append(n.layers, nextLayer)
 	}
 
+	fingerprint, err := n.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	n.fingerprint = fingerprint
+
 	// return the root hash
 	hash := n.layers[len(n.layers)-1][0].hash
 	return hash, nil
 }
 
-// consolidateLeaves extends the leaves in the tree and batches them into single
-// nodes as described in the paper
-func (n *NCMT) consolidateLeaves() error {
-	// erasure the leaf data
-	extendedLeaves, err := n.leaves.extend(n.opts.Codec)
-	if err != nil {
-		return err
-	}
+// consolidateLeaves batches the leaves in the tree along with their erasures
+// into single nodes as described in the paper
+func (n *NCMT) consolidateLeaves(extendedLeaves leaves) {
+	firstLayer := n.batchLeaves(n.leaves, extendedLeaves)
+	n.leaves = append(n.leaves, extendedLeaves...)
+	n.layers = append(n.layers, firstLayer)
+}
 
+// batchLeaves combines each batch of original leaves and their erasures into
+// a node of the first layer
+func (n *NCMT) batchLeaves(originals, extendedLeaves leaves) layer {
 	// batchSize is the amount of nodes from each: original and erasured to result in n.opts.BatchSize
 	batchSize := n.opts.BatchSize / 2
 	// create the next layer
-	firstLayer := make(layer, len(n.leaves)/batchSize)
+	firstLayer := make(layer, len(originals)/batchSize)
 
 	// batch the original and extended leaves together and combine into a single node
-	count := 0
-	for i := 0; i < len(n.leaves); i += batchSize {
+	hashTimer := startProfile(hashStage, 0)
+	forEachBatch(len(firstLayer), n.opts.Parallelism, func(count int) {
+		i := count * batchSize
 		j := i + batchSize
-		if j > len(n.leaves) {
-			j = len(n.leaves)
+		if j > len(originals) {
+			j = len(originals)
 		}
 		// use the first set of original leaves along with their erasures
-		batch := append(leaves{}, append(n.leaves[i:j], extendedLeaves[i:j]...)...)
+		batch := append(append(leaves{}, originals[i:j]...), extendedLeaves[i:j]...)
 		// to create a new node
-		firstLayer[count] = nodeFromLeaves(n.opts.FreshHash(), batch)
-		count++
-	}
-
-	n.leaves = append(n.leaves, extendedLeaves...)
-	n.layers = append(n.layers, firstLayer)
-
-	return nil
+		firstLayer[count] = nodeFromLeaves(n.opts.FreshHash(), batch, n.scheme())
+	})
+	hashTimer.stop()
+	return firstLayer
 }
 
 // consolidateNodes uses the last layer added, along with the erasures of that
 // data, to create the next layer of nodes
-func (n *NCMT) consolidateNodes() (layer, error) {
-	// creates erasure data of the first layer
-	latestLayer := n.layers[len(n.layers)-1]
-	extendedLayer, err := latestLayer.extend(n.opts.Codec)
+func (n *NCMT) consolidateNodes(ctx context.Context) (layer, error) {
+	extendedLayer, nextLayer, err := n.batchNodes(ctx, n.layers[len(n.layers)-1], len(n.layers))
 	if err != nil {
 		return nil, err
 	}
-
 	// add to the erasured layer
 	n.extendedLayers = append(n.extendedLayers, extendedLayer)
+	return nextLayer, nil
+}
+
+// batchNodes erasures the layer at level, and combines each batch of its
+// nodes and their erasures into a node of the next layer
+func (n *NCMT) batchNodes(ctx context.Context, latestLayer layer, level int) (layer, layer, error) {
+	// creates erasure data of the layer
+	_, span := n.startEncodeSpan(ctx, level)
+	encodeTimer := startProfile(encodeStage, level)
+	extendedLayer, err := latestLayer.extend(n.opts.Codec, n.opts.SegmentSize, n.opts.Parallelism)
+	encodeTimer.stop()
+	endSpan(span, err)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// parity nodes above the leaves use the reserved parity namespace instead
+	// of inheriting user namespaces
+	if n.opts.UniformParityNamespace {
+		parityID := n.parityNamespace()
+		for i := range extendedLayer {
+			extendedLayer[i].min = parityID
+			extendedLayer[i].max = parityID
+		}
+	}
 
 	// batchSize is the initial length of a batch of nodes
 	batchSize := n.opts.BatchSize / 2
@@ -222,15 +628,16 @@ func (n *NCMT) consolidateNodes() (layer, error) {
 	nextLayer := make(layer, len(latestLayer)/batchSize)
 
 	// batch the original and extended leaves together and combine into a single node
-	batchCount := 0
-	for i := 0; i < len(latestLayer); i += batchSize {
+	hashTimer := startProfile(hashStage, level)
+	forEachBatch(len(nextLayer), n.opts.Parallelism, func(batchCount int) {
+		i := batchCount * batchSize
 		j := i + batchSize
 		if j > len(latestLayer) {
 			j = len(latestLayer)
 		}
-		batch := append(layer{}, append(latestLayer[i:j], extendedLayer[i:j]...)...)
-		nextLayer[batchCount] = newNode(n.opts.FreshHash(), batch)
-		batchCount++
-	}
-	return nextLayer, nil
+		batch := append(append(layer{}, latestLayer[i:j]...), extendedLayer[i:j]...)
+		nextLayer[batchCount] = newNode(n.opts.FreshHash(), batch, n.scheme())
+	})
+	hashTimer.stop()
+	return extendedLayer, nextLayer, nil
 }
@@ -44,10 +44,10 @@ func TestLeavesExtension(t *testing.T) {
 	lvs := make(leaves, len(data))
 	for i, d := range data {
 		prefixed := namespace.NewPrefixedData(namespace.IDSize(1), d)
-		lvs[i] = newLeaf(sha256.New(), prefixed)
+		lvs[i] = newLeaf(sha256.New(), prefixed, hashScheme{})
 	}
 	codec := newRSFG8()
-	extended, err := lvs.extend(codec)
+	extended, err := lvs.extend(codec, 0, 1)
 	if err != nil {
 		t.Error(err)
 	}
@@ -69,7 +69,7 @@ func TestLayerExtension(t *testing.T) {
 		layer[i] = node{hash: []byte{byte(i + 1)}}
 	}
 	codec := newRSFG8()
-	extended, err := layer.extend(codec)
+	extended, err := layer.extend(codec, 0, 1)
 	if err != nil {
 		t.Error(err)
 	}
@@ -164,3 +164,275 @@ func BenchmarkNCMTCreation(b *testing.B) {
 		}
 	}
 }
+
+func TestLoadExtended(t *testing.T) {
+	data := mockData(16, 8)
+	tree := NewNCMT()
+	for _, d := range data {
+		err := tree.Push(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	extended, err := tree.leaves.extend(tree.opts.Codec, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedRoot, err := tree.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	originals := make([][]byte, len(data))
+	for i, d := range data {
+		originals[i] = append(append([]byte{}, d.NamespaceID()...), d.Data()...)
+	}
+	parity := extended.raw()
+
+	loaded := NewNCMT()
+	root, err := loaded.LoadExtended(originals, parity)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRoot, root)
+
+	// tampered parity is rejected, leaving the tree empty so that it can be
+	// loaded again
+	tampered := append([][]byte{}, parity...)
+	tampered[3] = append([]byte{}, parity[3]...)
+	tampered[3][0]++
+	retried := NewNCMT()
+	_, err = retried.LoadExtended(originals, tampered)
+	assert.Error(t, err)
+	assert.Empty(t, retried.leaves)
+	root, err = retried.LoadExtended(originals, parity)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRoot, root)
+
+	// mismatched lengths are rejected
+	_, err = NewNCMT().LoadExtended(originals, parity[1:])
+	assert.Error(t, err)
+}
+
+func TestLeafCounts(t *testing.T) {
+	// 3 leaves of namespace 0, 9 of namespace 1, and 4 of namespace 2
+	counts := []int{3, 9, 4}
+	var data []namespace.Data
+	for ns, count := range counts {
+		for _, d := range mockData(count, 4) {
+			data = append(data, namespace.PrefixedDataFrom(mockID(ns), d.Data()))
+		}
+	}
+	build := func(setters ...Option) *NCMT {
+		tree := NewNCMT(setters...)
+		for _, d := range data {
+			err := tree.Push(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		_, err := tree.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tree
+	}
+	counted := build(WithLeafCounts())
+	root := counted.layers[len(counted.layers)-1][0]
+	assert.Equal(t, uint64(3), root.minCount)
+	assert.Equal(t, uint64(4), root.maxCount)
+
+	// the first node of the first layer covers 2 leaves of namespace 0 and 2
+	// erasured leaves, which are not counted
+	first := counted.layers[0][0]
+	assert.Equal(t, uint64(2), first.minCount)
+	assert.Equal(t, uint64(2), first.maxCount)
+
+	// counts are only committed to when enabled
+	uncounted := build()
+	assert.NotEqual(t, counted.Root(), uncounted.Root())
+	assert.Equal(t, counted.Root()[:16], uncounted.Root()[:16])
+}
+
+func TestCommitmentVersion(t *testing.T) {
+	data := mockData(16, 8)
+	build := func(setters ...Option) ([]byte, error) {
+		tree := NewNCMT(setters...)
+		for _, d := range data {
+			err := tree.Push(d)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return tree.Build()
+	}
+	v0Root, err := build()
+	assert.NoError(t, err)
+	v1Root, err := build(WithCommitmentVersion(CommitmentV1))
+	assert.NoError(t, err)
+	assert.NotEqual(t, v0Root, v1Root)
+	// the namespace range of the root is unaffected by the version
+	assert.Equal(t, v0Root[:16], v1Root[:16])
+
+	_, err = build(WithCommitmentVersion(CommitmentV1 + 1))
+	assert.Error(t, err)
+}
+
+func TestMigrate(t *testing.T) {
+	v0 := sharedNamespaceTree(t, WithLeafCounts())
+	v0Root := v0.Root()
+	data := make([]namespace.Data, v0.OriginalWidth())
+	for i := range data {
+		d, err := v0.Get(uint(i))
+		assert.NoError(t, err)
+		data[i] = d
+	}
+	expected, err := ComputeRoot(data, NewNCMT(WithLeafCounts(), WithCommitmentVersion(CommitmentV1)).opts)
+	assert.NoError(t, err)
+
+	v1, err := v0.Migrate(CommitmentV1)
+	assert.NoError(t, err)
+	assert.Equal(t, CommitmentV1, v1.Options().CommitmentVersion)
+	assert.True(t, v1.Options().LeafCounts)
+	assert.Equal(t, expected, v1.Root())
+	// the migrated tree is independent of the original
+	assert.Equal(t, v0Root, v0.Root())
+
+	// proofs of either version verify with the header of their tree
+	for _, tree := range []*NCMT{v0, v1} {
+		header, err := tree.Header()
+		assert.NoError(t, err)
+		proof, err := tree.ProveNamespace(mockID(4))
+		assert.NoError(t, err)
+		assert.True(t, VerifyWithHeader(header, proof))
+	}
+
+	// migrating back recomputes the original root
+	back, err := v1.Migrate(CommitmentV0)
+	assert.NoError(t, err)
+	assert.Equal(t, v0Root, back.Root())
+
+	_, err = v0.Migrate(CommitmentV1 + 1)
+	assert.Error(t, err)
+	_, err = NewNCMT().Migrate(CommitmentV1)
+	assert.Error(t, err)
+	assert.NoError(t, v0.PrunePayloads(0, 1))
+	_, err = v0.Migrate(CommitmentV1)
+	assert.Error(t, err)
+}
+
+func TestParityNamespace(t *testing.T) {
+	tree := mockTree(16, 4, t)
+	parityID := namespace.ID{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	for _, l := range tree.extendedLayers {
+		for _, parity := range l {
+			assert.Equal(t, parityID, parity.min)
+			assert.Equal(t, parityID, parity.max)
+		}
+	}
+	// erasured leaves keep their original namespace
+	assert.Equal(t, tree.leaves[0].min, tree.leaves[16].min)
+	// parent ranges ignore the parity namespace
+	assert.Equal(t, mockID(3), tree.layers[1][0].max)
+	assert.Equal(t, mockID(15), tree.layers[len(tree.layers)-1][0].max)
+
+	// the parity namespace cannot be pushed
+	err := NewNCMT().Push(namespace.PrefixedDataFrom(parityID, []byte{1}))
+	assert.Error(t, err)
+
+	// without uniform parity namespaces, parity nodes inherit their originals'
+	// namespaces, and the root is unaffected either way
+	inherited := NewNCMT(func(o *Options) { o.UniformParityNamespace = false })
+	for i := range tree.originalLeaves() {
+		assert.NoError(t, inherited.Push(tree.leaves[i].data))
+	}
+	root, err := inherited.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, tree.Root(), root)
+	assert.Equal(t, inherited.layers[0][0].min, inherited.extendedLayers[0][0].min)
+}
+
+func TestParallelism(t *testing.T) {
+	data := mockData(128, 32)
+	build := func(setters ...Option) []byte {
+		tree := NewNCMT(setters...)
+		for _, d := range data {
+			assert.NoError(t, tree.Push(d))
+		}
+		root, err := tree.Build()
+		assert.NoError(t, err)
+		return root
+	}
+	serial := build()
+	for _, workers := range []int{0, 2, 3, 8, 1000} {
+		assert.Equal(t, serial, build(WithParallelism(workers)), workers)
+	}
+	assert.Equal(t, build(WithLeafCounts()), build(WithLeafCounts(), WithParallelism(4)))
+}
+
+func TestMaxLeaves(t *testing.T) {
+	tree := NewNCMT()
+	for _, d := range mockData(RSFG8{}.MaxLeaves(), 4) {
+		assert.NoError(t, tree.Push(d))
+	}
+	err := tree.Push(mockData(1, 4)[0])
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "at most 128 leaves")
+}
+
+func benchmarkBuild(b *testing.B, leafCount, leafSize, workers int) {
+	mockData := mockData(leafCount, leafSize)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tree := NewNCMT(WithParallelism(workers))
+		for _, d := range mockData {
+			err := tree.Push(d)
+			if err != nil {
+				b.Error(err)
+			}
+		}
+		_, err := tree.Build()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBuildSerial(b *testing.B)    { benchmarkBuild(b, 128, 4096, 1) }
+func BenchmarkBuildParallel2(b *testing.B) { benchmarkBuild(b, 128, 4096, 2) }
+func BenchmarkBuildParallel4(b *testing.B) { benchmarkBuild(b, 128, 4096, 4) }
+func BenchmarkBuildParallelCPU(b *testing.B) {
+	benchmarkBuild(b, 128, 4096, 0)
+}
+
+func TestValidate(t *testing.T) {
+	push := func(tree *NCMT, data []namespace.Data) *NCMT {
+		for _, d := range data {
+			assert.NoError(t, tree.Push(d))
+		}
+		return tree
+	}
+
+	tree := push(NewNCMT(), mockData(16, 8))
+	assert.NoError(t, tree.Validate())
+	_, err := tree.Build()
+	assert.NoError(t, err)
+	assert.Error(t, tree.Validate())
+
+	// the problems Build would reject are found without building
+	for name, tree := range map[string]*NCMT{
+		"empty":        NewNCMT(),
+		"indivisible":  push(NewNCMT(), mockData(6, 8)),
+		"uneven width": push(NewNCMT(), mockData(12, 8)),
+		"share sizes":  push(NewNCMT(), append(mockData(3, 8), mockData(4, 16)[3])),
+		"batch size 6": push(NewNCMT(func(o *Options) { o.BatchSize = 6 }), mockData(6, 8)),
+	} {
+		assert.Error(t, tree.Validate(), name)
+		_, err := tree.Build()
+		assert.Error(t, err, name)
+	}
+
+	// leaves modified after being pushed are rechecked
+	data := mockData(4, 8)
+	tree = push(NewNCMT(), data)
+	copy(tree.leaves[0].data.NamespaceID(), mockID(9))
+	assert.Error(t, tree.Validate())
+}
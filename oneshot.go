@@ -0,0 +1,46 @@
+package ncmt
+
+import (
+	"context"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// ComputeRoot returns the root of a tree of data, as Build would, without
+// retaining the tree. Only the layer being built and its erasure are kept in
+// memory, so validators that only check a proposed root avoid the memory of
+// every layer. data must be pushable in order, as with Push. If opts is nil,
+// the default options are used.
+func ComputeRoot(data []namespace.Data, opts *Options) ([]byte, error) {
+	if opts == nil {
+		opts = NewNCMT().opts
+	}
+	n := NewNCMT(preset(*opts))
+	for _, d := range data {
+		err := n.Push(d)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := n.checkBuildable()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	extendedLeaves, err := n.extendLeaves(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := n.batchLeaves(n.leaves, extendedLeaves)
+	// the leaves are no longer needed once the first layer is built
+	n.leaves, extendedLeaves = nil, nil
+
+	for level := 1; len(current) > 1; level++ {
+		_, next, err := n.batchNodes(ctx, current, level)
+		if err != nil {
+			return nil, err
+		}
+		current = next
+	}
+	return current[0].hash, nil
+}
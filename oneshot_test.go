@@ -0,0 +1,47 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/lazyledger/nmt/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeRoot(t *testing.T) {
+	data := mockData(64, 16)
+	for _, setters := range [][]Option{
+		nil,
+		{WithLeafCounts(), WithCommitmentVersion(CommitmentV1)},
+		{WithSegmentSize(16), WithParallelism(4)},
+		{func(o *Options) { o.UniformParityNamespace = false }},
+		{WithCommitmentVersion(CommitmentV1), WithSalt([]byte("salt"))},
+	} {
+		tree := NewNCMT(setters...)
+		for _, d := range data {
+			assert.NoError(t, tree.Push(d))
+		}
+		root, err := tree.Build()
+		assert.NoError(t, err)
+
+		opts := tree.Options()
+		computed, err := ComputeRoot(data, &opts)
+		assert.NoError(t, err)
+		assert.Equal(t, root, computed)
+	}
+
+	root, err := ComputeRoot(data, nil)
+	assert.NoError(t, err)
+	tree := NewNCMT()
+	for _, d := range data {
+		assert.NoError(t, tree.Push(d))
+	}
+	expected, err := tree.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, root)
+
+	// the same problems as Push and Build are reported
+	_, err = ComputeRoot(data[:6], nil)
+	assert.Error(t, err)
+	_, err = ComputeRoot([]namespace.Data{data[1], data[0]}, nil)
+	assert.Error(t, err)
+}
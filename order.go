@@ -0,0 +1,22 @@
+package ncmt
+
+// orderDomain separates order commitments from leaf and node hashes
+var orderDomain = []byte("ncmt-order")
+
+// OrderCommitment returns a hash of the ordered sequence of (namespace,
+// leafHash) pairs of the original leaves, including any padding. It is
+// independent of the erasure and layer construction, so builders that produce
+// the same root can compare order commitments to confirm that they started
+// from identically ordered and padded inputs, and builders that produce
+// different roots can narrow the divergence down to their share pipelines.
+func (n *NCMT) OrderCommitment() []byte {
+	h := n.opts.FreshHash()
+	h.Write(orderDomain)
+	originals := n.originalLeaves()
+	writeUint64(h, uint64(len(originals)))
+	for _, lf := range originals {
+		writeLenPrefixed(h, lf.min)
+		writeLenPrefixed(h, lf.hash)
+	}
+	return h.Sum(nil)
+}
@@ -0,0 +1,32 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/lazyledger/nmt/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderCommitment(t *testing.T) {
+	data := mockData(8, 8)
+	// give the first two leaves the same namespace so they can be swapped
+	data[1] = namespace.PrefixedDataFrom(append(namespace.ID{}, data[0].NamespaceID()...), data[1].Data())
+	commit := func(data []namespace.Data) []byte {
+		tree := NewNCMT()
+		for _, d := range data {
+			assert.NoError(t, tree.Push(d))
+		}
+		before := tree.OrderCommitment()
+		_, err := tree.Build()
+		assert.NoError(t, err)
+		// building does not affect the commitment
+		assert.Equal(t, before, tree.OrderCommitment())
+		return before
+	}
+
+	original := commit(data)
+	assert.Equal(t, original, commit(data))
+
+	swapped := append([]namespace.Data{data[1], data[0]}, data[2:]...)
+	assert.NotEqual(t, original, commit(swapped))
+}
@@ -0,0 +1,87 @@
+package ncmt
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// PaddingNamespace is the namespace reserved for padding shares, which is the
+// largest possible namespace of the given size below the reserved parity
+// namespace. As it sorts after every application namespace, padding is always
+// placed at the end of the original leaves.
+func PaddingNamespace(size namespace.IDSize) namespace.ID {
+	id := genParityNameSpaceID(int8(size))
+	id[len(id)-1] = 0xFE
+	return id
+}
+
+// PaddingShare creates a padding share, which has the padding namespace and an
+// all zero payload of shareSize bytes
+func PaddingShare(nsSize namespace.IDSize, shareSize int) NamespacedData {
+	return NamespacedData{
+		ID:      PaddingNamespace(nsSize),
+		Payload: make([]byte, shareSize),
+	}
+}
+
+// IsPadding returns true if data belongs to the padding namespace. Push only
+// accepts padding shares in that namespace, so application data cannot be
+// mistaken for padding.
+func IsPadding(data namespace.Data) bool {
+	id := data.NamespaceID()
+	return len(id) > 0 && id.Equal(PaddingNamespace(id.Size()))
+}
+
+// checkPadding ensures that data in the padding namespace is a padding share
+func checkPadding(data namespace.Data) error {
+	if !IsPadding(data) {
+		return nil
+	}
+	for _, b := range data.Data() {
+		if b != 0 {
+			return errors.New("namespace.ID is reserved for padding shares")
+		}
+	}
+	return nil
+}
+
+// Padding reports which of the proven leaves are padding shares, so that
+// verifiers can exclude them from application data. Leaves in the padding
+// namespace with a non zero payload, which Push rejects, are not reported as
+// padding. nsSize must match the namespace size of the tree.
+func (p Proof) Padding(nsSize namespace.IDSize) []bool {
+	padding := make([]bool, len(p.Data))
+	for i, raw := range p.Data {
+		data, err := ParseNamespacedData(nsSize, raw)
+		padding[i] = err == nil && IsPadding(data) && checkPadding(data) == nil
+	}
+	return padding
+}
+
+// AutoPad pushes padding shares of shareSize bytes until the number of leaves
+// is the smallest width that can be built, which is a power of half the batch
+// size that is also a multiple of the batch size. The tree must not be empty.
+func (n *NCMT) AutoPad(shareSize int) error {
+	if len(n.leaves) == 0 {
+		return errors.New("cannot pad an empty tree")
+	}
+	// the batch size is a power of two, so the width below is reached
+	err := checkBatchSize(n.opts.BatchSize)
+	if err != nil {
+		return fmt.Errorf("cannot pad the tree: %s", err)
+	}
+	batchSize := n.opts.BatchSize / 2
+	width := batchSize
+	for width < len(n.leaves) || width%n.opts.BatchSize != 0 {
+		width *= batchSize
+	}
+	for len(n.leaves) < width {
+		err := n.Push(PaddingShare(n.opts.NamespaceSize, shareSize))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaddingShare(t *testing.T) {
+	share := PaddingShare(8, 4)
+	assert.Equal(t, NamespacedData{
+		ID:      []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFE},
+		Payload: []byte{0, 0, 0, 0},
+	}, share)
+	assert.True(t, IsPadding(share))
+	assert.False(t, IsPadding(NewNamespacedData(mockID(1), []byte{0, 0, 0, 0})))
+	// the padding namespace sorts between application data and parity
+	assert.True(t, mockID(255).Less(share.ID))
+	assert.True(t, share.ID.Less(genParityNameSpaceID(8)))
+}
+
+func TestAutoPad(t *testing.T) {
+	for _, tc := range []struct {
+		leaves, batchSize, expected int
+	}{
+		{1, 4, 4},
+		{5, 4, 8},
+		{16, 4, 16},
+		{17, 4, 32},
+		{3, 8, 16},
+		{17, 8, 64},
+	} {
+		tree := NewNCMT(func(o *Options) { o.BatchSize = tc.batchSize })
+		for i := 0; i < tc.leaves; i++ {
+			assert.NoError(t, tree.Push(NewNamespacedData(mockID(i), []byte{1, 2})))
+		}
+		assert.NoError(t, tree.AutoPad(2))
+		assert.Len(t, tree.leaves, tc.expected, tc)
+		_, err := tree.Build()
+		assert.NoError(t, err)
+
+		// namespace proofs mark the padding so that it can be excluded
+		proof, err := tree.ProveNamespace(PaddingNamespace(8))
+		assert.NoError(t, err)
+		if tc.leaves == tc.expected {
+			assert.True(t, proof.Absence())
+			continue
+		}
+		assert.True(t, Verify(tree.Root(), tree.opts, proof))
+		for _, padding := range proof.Padding(8) {
+			assert.True(t, padding)
+		}
+		proof, err = tree.ProveRange(0, uint(tc.expected))
+		assert.NoError(t, err)
+		padding := proof.Padding(8)
+		for i := range padding {
+			assert.Equal(t, i >= tc.leaves, padding[i])
+		}
+	}
+
+	assert.Error(t, NewNCMT().AutoPad(2))
+	// no width can be built with a batch size of 6
+	tree := NewNCMT(func(o *Options) { o.BatchSize = 6 })
+	assert.NoError(t, tree.Push(NewNamespacedData(mockID(1), []byte{1, 2})))
+	assert.Error(t, tree.AutoPad(2))
+}
+
+func TestReservedPadding(t *testing.T) {
+	tree := NewNCMT()
+	assert.NoError(t, tree.Push(NewNamespacedData(mockID(1), []byte{1, 2})))
+	// data that is not a padding share cannot use the padding namespace
+	spoofed := NewNamespacedData(PaddingNamespace(8), []byte{0, 1})
+	assert.Error(t, tree.Push(spoofed))
+	assert.NoError(t, tree.AutoPad(2))
+	assert.NoError(t, tree.Validate())
+	// padding modified after being pushed is rechecked
+	tree.leaves[3].data.Data()[1] = 1
+	assert.Error(t, tree.Validate())
+
+	// proofs from trees that do not reserve the namespace are not trusted
+	raw := append(append([]byte{}, spoofed.NamespaceID()...), spoofed.Data()...)
+	proof := Proof{Data: [][]byte{raw, append(PaddingNamespace(8), 0, 0)}}
+	assert.Equal(t, []bool{false, true}, proof.Padding(8))
+}
@@ -0,0 +1,40 @@
+package ncmt
+
+import (
+	"runtime"
+	"sync"
+)
+
+// forEachBatch calls fn for every index in [0, count) using up to workers
+// goroutines. Each call must only write to state owned by its index.
+func forEachBatch(count, workers int, fn func(i int)) {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > count {
+		workers = count
+	}
+	if workers <= 1 {
+		for i := 0; i < count; i++ {
+			fn(i)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	// hand out contiguous chunks so that each worker touches nearby memory
+	chunk := (count + workers - 1) / workers
+	for start := 0; start < count; start += chunk {
+		end := start + chunk
+		if end > count {
+			end = count
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				fn(i)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,146 @@
+package ncmt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ParityProof shows that a range of parity symbols of a single layer is
+// committed to by a root. It reveals no original data, only hashes, so that
+// storage providers holding only parity can be audited.
+type ParityProof struct {
+	// Layer is the layer of the symbols, where layer 0 is the leaf layer
+	Layer int `cbor:"layer"`
+	// Start and End are the range of proven parity symbols [Start, End),
+	// counted from the first parity symbol of the layer
+	Start uint `cbor:"start"`
+	End   uint `cbor:"end"`
+	// Width is the number of original leaves in the tree
+	Width uint `cbor:"width"`
+	// Symbols holds the proven parity symbols. Parity leaves are prefixed with
+	// their namespace, like the data of a Proof, while parity nodes are the
+	// erasured hashes of the layer.
+	Symbols [][]byte    `cbor:"symbols"`
+	Set     [][]byte    `cbor:"set"`
+	Counts  [][2]uint64 `cbor:"counts"`
+}
+
+// ProveParityRange creates a proof of the parity symbols [start, end) of the
+// layer. Layers above the leaves are the nodes of the tree, and every layer
+// below the root has parity.
+func (n *NCMT) ProveParityRange(layer int, start, end uint) (ParityProof, error) {
+	if len(n.layers) == 0 {
+		return ParityProof{}, errors.New("tree must be built before creating proofs")
+	}
+	if layer < 0 || layer >= n.Depth() {
+		return ParityProof{}, fmt.Errorf("layer %d has no parity, the tree has %d layers below its root", layer, n.Depth())
+	}
+	width := n.levelWidth(layer)
+	if start >= end || end > width {
+		return ParityProof{}, fmt.Errorf("invalid range [%d, %d) of the %d parity symbols of layer %d", start, end, width, layer)
+	}
+	if layer == 0 {
+		err := n.checkPayloads(width+start, width+end)
+		if err != nil {
+			return ParityProof{}, err
+		}
+	}
+
+	proof := ParityProof{
+		Layer: layer,
+		Start: start,
+		End:   end,
+		Width: n.originalWidth,
+	}
+	for pos := width + start; pos < width+end; pos++ {
+		if layer == 0 {
+			lf := n.leaves[pos]
+			proof.Symbols = append(proof.Symbols, NamespacedData{
+				ID:      lf.data.NamespaceID(),
+				Payload: lf.data.Data(),
+			}.Bytes())
+			continue
+		}
+		proof.Symbols = append(proof.Symbols, append([]byte{}, n.symbol(layer, pos).hash...))
+	}
+	plan := ProofPlan{Level: layer, Known: rangePositions(width+start, width+end)}
+	n.planSiblings(context.Background(), &plan)
+	proof.Set, proof.Counts = n.siblingHashes(plan.Siblings)
+	return proof, nil
+}
+
+// VerifyParity checks that p is valid for root. If opts is nil, the default
+// options are used.
+func VerifyParity(root []byte, opts *Options, p ParityProof) bool {
+	if opts == nil {
+		opts = NewNCMT().opts
+	}
+	return verifyParity(root, opts, p) == nil
+}
+
+// verifyParity returns a description of the first problem found with p
+func verifyParity(root []byte, opts *Options, p ParityProof) error {
+	batchSize := uint(opts.BatchSize / 2)
+	err := checkBatchSize(opts.BatchSize)
+	if err != nil {
+		return err
+	}
+	err = opts.checkHash()
+	if err != nil {
+		return err
+	}
+	if p.Layer < 0 {
+		return fmt.Errorf("invalid layer %d", p.Layer)
+	}
+	width, err := parityLayerWidth(p.Width, p.Layer, batchSize)
+	if err != nil {
+		return err
+	}
+	if p.Start >= p.End || p.End > width {
+		return fmt.Errorf("invalid range [%d, %d) of the %d parity symbols of layer %d", p.Start, p.End, width, p.Layer)
+	}
+	if uint(len(p.Symbols)) != p.End-p.Start {
+		return fmt.Errorf("expected %d parity symbols, received %d", p.End-p.Start, len(p.Symbols))
+	}
+
+	scheme := hashScheme{
+		version:      opts.CommitmentVersion,
+		commitCounts: opts.LeafCounts,
+		salt:         opts.Salt,
+	}
+	known := rangePositions(width+p.Start, width+p.End)
+	values := make(map[uint]node, len(known))
+	for i, symbol := range p.Symbols {
+		if p.Layer > 0 {
+			values[known[i]] = node{hash: symbol, parity: true}
+			continue
+		}
+		data, err := ParseNamespacedData(opts.NamespaceSize, symbol)
+		if err != nil {
+			return fmt.Errorf("invalid parity leaf: %s", err)
+		}
+		lf := newLeaf(opts.FreshHash(), data, scheme)
+		lf.node.parity = true
+		lf.node.minCount, lf.node.maxCount = 0, 0
+		values[known[i]] = lf.node
+	}
+	noCheck := func(child, lowest, highest uint, sibling node) error { return nil }
+	return climb(root, opts, scheme, p.Layer, width, known, values, p.Set, p.Counts, noCheck)
+}
+
+// parityLayerWidth returns the number of original symbols in layer of a tree
+// with width original leaves, ensuring that the layer has parity
+func parityLayerWidth(width uint, layer int, batchSize uint) (uint, error) {
+	layerWidth := width
+	for i := 0; i < layer; i++ {
+		if layerWidth%batchSize != 0 || layerWidth < batchSize {
+			return 0, fmt.Errorf("layer %d is outside of a tree of width %d", layer, width)
+		}
+		layerWidth = layerWidth / batchSize
+	}
+	if layerWidth < 2 {
+		return 0, fmt.Errorf("layer %d has no parity", layer)
+	}
+	return layerWidth, nil
+}
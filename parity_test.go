@@ -0,0 +1,60 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProveParityRange(t *testing.T) {
+	for _, setters := range [][]Option{
+		nil,
+		{WithLeafCounts()},
+		{WithCommitmentVersion(CommitmentV1), WithLeafCounts()},
+		{func(o *Options) { o.UniformParityNamespace = true }},
+	} {
+		tree := sharedNamespaceTree(t, setters...)
+		opts := tree.Options()
+		root := tree.Root()
+		// layer widths are 16, 8, 4 and 2
+		for layer := 0; layer < tree.Depth(); layer++ {
+			width := tree.levelWidth(layer)
+			for _, r := range [][2]uint{{0, 1}, {0, width}, {width - 1, width}, {1, width / 2}} {
+				if r[0] >= r[1] {
+					continue
+				}
+				proof, err := tree.ProveParityRange(layer, r[0], r[1])
+				assert.NoError(t, err)
+				assert.True(t, VerifyParity(root, &opts, proof), "layer %d range %v", layer, r)
+
+				tampered := proof
+				tampered.Symbols = append([][]byte{}, proof.Symbols...)
+				tampered.Symbols[0] = append([]byte{}, proof.Symbols[0]...)
+				tampered.Symbols[0][len(tampered.Symbols[0])-1]++
+				assert.False(t, VerifyParity(root, &opts, tampered))
+
+				moved := proof
+				moved.Layer++
+				assert.False(t, VerifyParity(root, &opts, moved))
+			}
+		}
+	}
+
+	tree := sharedNamespaceTree(t)
+	_, err := tree.ProveParityRange(tree.Depth(), 0, 1)
+	assert.Error(t, err)
+	_, err = tree.ProveParityRange(0, 0, 17)
+	assert.Error(t, err)
+	_, err = tree.ProveParityRange(0, 2, 2)
+	assert.Error(t, err)
+	_, err = NewNCMT().ProveParityRange(0, 0, 1)
+	assert.Error(t, err)
+
+	// parity leaves prove the same symbols as a range proof of the leaves
+	proof, err := tree.ProveParityRange(0, 3, 5)
+	assert.NoError(t, err)
+	leaves, err := tree.ProveRange(19, 21)
+	assert.NoError(t, err)
+	assert.Equal(t, leaves.Data, proof.Symbols)
+	assert.Equal(t, leaves.Set, proof.Set)
+}
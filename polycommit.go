@@ -0,0 +1,297 @@
+//go:build ncmtexperimental
+
+package ncmt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// This file contains an experimental alternative to the batched hash layers of
+// the NCMT, intended for research comparisons only. The extended leaf layer is
+// committed to with a flat binary merkle tree, and correct erasure is shown by
+// a Ligero style consistency check: random combinations of the symbols' byte
+// columns must themselves be codewords, which is checked against a few opened
+// symbols. Unlike the batched layers, the check requires no decoding by the
+// verifier, but it only tests the encoding of the leaf layer and is sound only
+// for codecs that are linear over the bytes of each symbol, i.e. RSFG8.
+
+const (
+	// polyChallenges is the number of random combinations of byte columns.
+	// Each combination misses an incorrect column with probability 1/2.
+	polyChallenges = 32
+	// polyQueries is the number of symbols opened to check the combinations
+	polyQueries = 16
+)
+
+// PolyCommitment commits to the extended leaves of a tree along with a
+// non-interactive proof that they were erasured correctly
+type PolyCommitment struct {
+	// Root is the binary merkle root of the extended leaves
+	Root  []byte `cbor:"root"`
+	Width uint   `cbor:"width"`
+	// Combined holds a combination of the extended leaves' byte columns for
+	// every challenge
+	Combined [][]byte      `cbor:"combined"`
+	Openings []PolyOpening `cbor:"openings"`
+}
+
+// PolyOpening reveals a single extended leaf of a PolyCommitment
+type PolyOpening struct {
+	Index uint `cbor:"index"`
+	// Data is the namespace and payload of the leaf
+	Data []byte   `cbor:"data"`
+	Path [][]byte `cbor:"path"`
+}
+
+// PolyCommit creates a PolyCommitment of the extended leaves of a built tree
+func (n *NCMT) PolyCommit() (PolyCommitment, error) {
+	if len(n.layers) == 0 {
+		return PolyCommitment{}, errors.New("tree must be built before committing")
+	}
+	if n.opts.Codec.ID() != CodecRSGF8 {
+		return PolyCommitment{}, fmt.Errorf("polynomial commitments do not support codec %s", n.opts.Codec.ID())
+	}
+	err := n.checkPayloads(0, uint(len(n.leaves)))
+	if err != nil {
+		return PolyCommitment{}, err
+	}
+	nsSize := int(n.opts.NamespaceSize)
+	hashes := make([][]byte, len(n.leaves))
+	for i, lf := range n.leaves {
+		hashes[i] = polyLeafHash(n.opts, polyLeafData(lf))
+	}
+	c := PolyCommitment{
+		Root:  polyRoot(n.opts, hashes),
+		Width: n.originalWidth,
+	}
+	seed := polySeed(n.opts, c.Root, c.Width)
+	size := len(n.leaves[0].data.Data())
+	for j := 0; j < polyChallenges; j++ {
+		mask := polyMask(n.opts, seed, j, size)
+		combined := make([]byte, len(n.leaves))
+		for i, lf := range n.leaves {
+			combined[i] = combineColumns(mask, polyLeafData(lf)[nsSize:])
+		}
+		c.Combined = append(c.Combined, combined)
+	}
+	for _, idx := range polyQueryIndices(n.opts, seed, c.Combined, uint(len(n.leaves))) {
+		c.Openings = append(c.Openings, PolyOpening{
+			Index: idx,
+			Data:  polyLeafData(n.leaves[idx]),
+			Path:  polyPath(n.opts, hashes, idx),
+		})
+	}
+	return c, nil
+}
+
+// VerifyPolyCommitment checks that c commits to a correctly erasured leaf
+// layer. If opts is nil, the default options are used.
+func VerifyPolyCommitment(opts *Options, c PolyCommitment) bool {
+	if opts == nil {
+		opts = NewNCMT().opts
+	}
+	return verifyPoly(opts, c) == nil
+}
+
+// verifyPoly returns a description of the first problem found with c
+func verifyPoly(opts *Options, c PolyCommitment) error {
+	err := opts.checkHash()
+	if err != nil {
+		return err
+	}
+	if opts.Codec.ID() != CodecRSGF8 {
+		return fmt.Errorf("polynomial commitments do not support codec %s", opts.Codec.ID())
+	}
+	width := 2 * c.Width
+	if c.Width == 0 || len(c.Combined) != polyChallenges || len(c.Openings) != polyQueries {
+		return errors.New("malformed polynomial commitment")
+	}
+	for j, combined := range c.Combined {
+		if uint(len(combined)) != width {
+			return fmt.Errorf("combination %d has %d symbols, expected %d", j, len(combined), width)
+		}
+		originals := make([][]byte, c.Width)
+		for i := range originals {
+			originals[i] = combined[i : i+1]
+		}
+		parity, err := encodeSegments(opts.Codec, originals, opts.SegmentSize, 1)
+		if err != nil {
+			return fmt.Errorf("failure to encode combination %d: %s", j, err)
+		}
+		for i, symbol := range parity {
+			if !bytes.Equal(symbol, combined[c.Width+uint(i):c.Width+uint(i)+1]) {
+				return fmt.Errorf("combination %d is not a codeword", j)
+			}
+		}
+	}
+
+	nsSize := int(opts.NamespaceSize)
+	seed := polySeed(opts, c.Root, c.Width)
+	indices := polyQueryIndices(opts, seed, c.Combined, width)
+	size := len(c.Openings[0].Data) - nsSize
+	if size < 0 {
+		return errors.New("opened leaf is shorter than a namespace")
+	}
+	masks := make([][]byte, polyChallenges)
+	for j := range masks {
+		masks[j] = polyMask(opts, seed, j, size)
+	}
+	for q, opening := range c.Openings {
+		if opening.Index != indices[q] {
+			return fmt.Errorf("opening %d is of leaf %d, expected %d", q, opening.Index, indices[q])
+		}
+		if len(opening.Data)-nsSize != size {
+			return fmt.Errorf("opened leaf %d has the wrong size", opening.Index)
+		}
+		root, err := polyPathRoot(opts, polyLeafHash(opts, opening.Data), opening.Index, width, opening.Path)
+		if err != nil {
+			return fmt.Errorf("invalid path of opened leaf %d: %s", opening.Index, err)
+		}
+		if !bytes.Equal(root, c.Root) {
+			return fmt.Errorf("opened leaf %d is not committed to by the root", opening.Index)
+		}
+		for j, mask := range masks {
+			if combineColumns(mask, opening.Data[nsSize:]) != c.Combined[j][opening.Index] {
+				return fmt.Errorf("opened leaf %d does not match combination %d", opening.Index, j)
+			}
+		}
+	}
+	return nil
+}
+
+func polyLeafData(lf leaf) []byte {
+	return append(append([]byte{}, lf.data.NamespaceID()...), lf.data.Data()...)
+}
+
+// polySeed is the Fiat-Shamir seed all challenges are derived from
+func polySeed(opts *Options, root []byte, width uint) []byte {
+	h := opts.FreshHash()
+	h.Write([]byte("ncmt-poly"))
+	h.Write(root)
+	binary.Write(h, binary.BigEndian, uint64(width))
+	return h.Sum(nil)
+}
+
+// polyMask selects a random subset of the size byte columns for challenge j
+func polyMask(opts *Options, seed []byte, j, size int) []byte {
+	mask := make([]byte, 0, (size+7)/8)
+	for counter := uint64(0); len(mask) < cap(mask); counter++ {
+		h := opts.FreshHash()
+		h.Write(seed)
+		binary.Write(h, binary.BigEndian, uint64(j))
+		binary.Write(h, binary.BigEndian, counter)
+		mask = append(mask, h.Sum(nil)...)
+	}
+	return mask[:cap(mask)]
+}
+
+// combineColumns XORs the bytes of symbol selected by mask
+func combineColumns(mask, symbol []byte) byte {
+	var combined byte
+	for i, b := range symbol {
+		if mask[i/8]&(1<<(i%8)) != 0 {
+			combined ^= b
+		}
+	}
+	return combined
+}
+
+// polyQueryIndices derives the leaves to open from the combinations, so that
+// they cannot be chosen before the combinations are fixed
+func polyQueryIndices(opts *Options, seed []byte, combined [][]byte, width uint) []uint {
+	h := opts.FreshHash()
+	h.Write(seed)
+	for _, c := range combined {
+		h.Write(c)
+	}
+	querySeed := h.Sum(nil)
+	indices := make([]uint, polyQueries)
+	for q := range indices {
+		h := opts.FreshHash()
+		h.Write(querySeed)
+		binary.Write(h, binary.BigEndian, uint64(q))
+		indices[q] = uint(binary.BigEndian.Uint64(h.Sum(nil)) % uint64(width))
+	}
+	return indices
+}
+
+// The leaves are committed to with an RFC 6962 style merkle tree, which
+// supports any number of leaves.
+
+func polyLeafHash(opts *Options, data []byte) []byte {
+	h := opts.FreshHash()
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func polyNodeHash(opts *Options, left, right []byte) []byte {
+	h := opts.FreshHash()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// polySplit is the largest power of two less than n
+func polySplit(n uint) uint {
+	k := uint(1)
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+func polyRoot(opts *Options, hashes [][]byte) []byte {
+	if len(hashes) == 1 {
+		return hashes[0]
+	}
+	k := polySplit(uint(len(hashes)))
+	return polyNodeHash(opts, polyRoot(opts, hashes[:k]), polyRoot(opts, hashes[k:]))
+}
+
+// polyPath returns the siblings of the leaf at idx, from the leaf to the root
+func polyPath(opts *Options, hashes [][]byte, idx uint) [][]byte {
+	if len(hashes) == 1 {
+		return nil
+	}
+	k := polySplit(uint(len(hashes)))
+	if idx < k {
+		return append(polyPath(opts, hashes[:k], idx), polyRoot(opts, hashes[k:]))
+	}
+	return append(polyPath(opts, hashes[k:], idx-k), polyRoot(opts, hashes[:k]))
+}
+
+// polyPathRoot computes the root of a tree of width leaves from the hash of
+// the leaf at idx and its path
+func polyPathRoot(opts *Options, hash []byte, idx, width uint, path [][]byte) ([]byte, error) {
+	if idx >= width {
+		return nil, fmt.Errorf("leaf %d is outside of a tree of width %d", idx, width)
+	}
+	if width == 1 {
+		if len(path) != 0 {
+			return nil, errors.New("path is too long")
+		}
+		return hash, nil
+	}
+	if len(path) == 0 {
+		return nil, errors.New("path is too short")
+	}
+	k := polySplit(width)
+	sibling, rest := path[len(path)-1], path[:len(path)-1]
+	if idx < k {
+		left, err := polyPathRoot(opts, hash, idx, k, rest)
+		if err != nil {
+			return nil, err
+		}
+		return polyNodeHash(opts, left, sibling), nil
+	}
+	right, err := polyPathRoot(opts, hash, idx-k, width-k, rest)
+	if err != nil {
+		return nil, err
+	}
+	return polyNodeHash(opts, sibling, right), nil
+}
@@ -0,0 +1,59 @@
+//go:build ncmtexperimental
+
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolyCommitment(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	opts := tree.Options()
+	commitment, err := tree.PolyCommit()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(16), commitment.Width)
+	assert.NoError(t, verifyPoly(&opts, commitment))
+	assert.True(t, VerifyPolyCommitment(nil, commitment))
+
+	tampered, err := tree.PolyCommit()
+	assert.NoError(t, err)
+	tampered.Combined[0][3] ^= 1
+	assert.False(t, VerifyPolyCommitment(&opts, tampered))
+
+	tampered, err = tree.PolyCommit()
+	assert.NoError(t, err)
+	tampered.Openings[0].Data[len(tampered.Openings[0].Data)-1] ^= 1
+	assert.False(t, VerifyPolyCommitment(&opts, tampered))
+
+	tampered, err = tree.PolyCommit()
+	assert.NoError(t, err)
+	tampered.Root[0] ^= 1
+	assert.False(t, VerifyPolyCommitment(&opts, tampered))
+
+	// incorrectly erasured parity is caught without decoding
+	bad := sharedNamespaceTree(t, WithCodec(badCodec{}))
+	commitment, err = bad.PolyCommit()
+	assert.NoError(t, err)
+	assert.False(t, VerifyPolyCommitment(&opts, commitment))
+
+	_, err = NewNCMT().PolyCommit()
+	assert.Error(t, err)
+}
+
+func TestPolyPath(t *testing.T) {
+	opts := NewNCMT().opts
+	for width := 1; width <= 9; width++ {
+		hashes := make([][]byte, width)
+		for i := range hashes {
+			hashes[i] = polyLeafHash(opts, []byte{byte(i)})
+		}
+		root := polyRoot(opts, hashes)
+		for i := range hashes {
+			computed, err := polyPathRoot(opts, hashes[i], uint(i), uint(width), polyPath(opts, hashes, uint(i)))
+			assert.NoError(t, err)
+			assert.Equal(t, root, computed)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package ncmt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PooledProof is a Proof whose buffers are borrowed from a pool shared by
+// every tree, so that servers answering many sample requests can reuse the
+// memory of proofs they have already sent. Release must be called exactly
+// once, after the proof is no longer needed. Neither the PooledProof nor any
+// slice of its Proof may be used after it is released.
+type PooledProof struct {
+	Proof
+
+	// the backing buffers of Proof, kept across uses
+	buf      []byte
+	data     [][]byte
+	set      [][]byte
+	counts   [][2]uint64
+	siblings []NodeCoordinate
+}
+
+// maxPooledDataSize limits the leaf data kept by a released proof, so that a
+// single proof of a large range does not pin its buffer in the pool
+const maxPooledDataSize = 1 << 20
+
+var proofPool = sync.Pool{
+	New: func() interface{} {
+		return new(PooledProof)
+	},
+}
+
+// Release returns the buffers of p to the pool. Releasing a nil proof does
+// nothing.
+func (p *PooledProof) Release() {
+	if p == nil {
+		return
+	}
+	if cap(p.buf) > maxPooledDataSize {
+		return
+	}
+	p.Proof = Proof{}
+	// sibling hashes belong to the tree, and must not be kept alive by the pool
+	for i := range p.set {
+		p.set[i] = nil
+	}
+	p.buf = p.buf[:0]
+	p.data = p.data[:0]
+	p.set = p.set[:0]
+	p.counts = p.counts[:0]
+	p.siblings = p.siblings[:0]
+	proofPool.Put(p)
+}
+
+// ProveLeafPooled performs ProveLeaf, filling a proof taken from the pool. The
+// leaf data is copied into the pooled buffers, while sibling hashes refer to
+// the tree, which must not be modified while the proof is in use.
+func (n *NCMT) ProveLeafPooled(idx uint) (proof *PooledProof, err error) {
+	_, span := startSpan(context.Background(), n.opts.Tracer, "ncmt.ProveRange",
+		attribute.Int64("ncmt.start", int64(idx)),
+		attribute.Int64("ncmt.end", int64(idx+1)),
+	)
+	defer func() {
+		if proof != nil {
+			span.SetAttributes(proofAttrs(proof.Proof)...)
+		}
+		endSpan(span, err)
+	}()
+	if len(n.layers) == 0 {
+		return nil, errors.New("tree must be built before creating proofs")
+	}
+	err = checkProofRange(idx, idx+1, n.originalWidth)
+	if err != nil {
+		return nil, err
+	}
+	err = n.checkPayloads(idx, idx+1)
+	if err != nil {
+		return nil, err
+	}
+	proof = proofPool.Get().(*PooledProof)
+	plan := n.newPlan(idx, idx+1)
+	plan.Siblings = proof.siblings[:0]
+	// without a deadline, planning cannot fail
+	_ = n.planSiblings(context.Background(), &plan)
+	proof.siblings = plan.Siblings
+	n.collectInto(plan, proof)
+	return proof, nil
+}
+
+// SamplePooled performs Sample using pooled proofs. Each of the returned
+// proofs must be released once it has been served. If any index cannot be
+// proven, the proofs created so far are released and an error is returned.
+func (n *NCMT) SamplePooled(indices []uint) ([]*PooledProof, error) {
+	samples := make([]*PooledProof, len(indices))
+	for i, idx := range indices {
+		proof, err := n.ProveLeafPooled(idx)
+		if err != nil {
+			for _, sample := range samples[:i] {
+				sample.Release()
+			}
+			return nil, fmt.Errorf("failure to sample symbol %d: %s", idx, err)
+		}
+		samples[i] = proof
+	}
+	return samples, nil
+}
+
+// collectInto fills p with the proof of a completed plan, reusing its buffers.
+// The result encodes identically to the proof created by collect.
+func (n *NCMT) collectInto(plan ProofPlan, p *PooledProof) {
+	leaves := n.leaves[plan.Start:plan.End]
+	size := 0
+	for _, lf := range leaves {
+		size += len(lf.data.NamespaceID()) + len(lf.data.Data())
+	}
+	// the buffer is sized up front, so that the data slices into it are not
+	// invalidated by a reallocation
+	if cap(p.buf) < size {
+		p.buf = make([]byte, 0, size)
+	}
+	buf := p.buf[:0]
+	data := p.data[:0]
+	for _, lf := range leaves {
+		offset := len(buf)
+		buf = append(buf, lf.data.NamespaceID()...)
+		buf = append(buf, lf.data.Data()...)
+		data = append(data, buf[offset:len(buf):len(buf)])
+	}
+	p.buf, p.data = buf, data
+	p.set, p.counts = n.appendSiblingHashes(p.set[:0], p.counts[:0], plan.Siblings)
+
+	p.Proof = Proof{
+		Start: plan.Start,
+		End:   plan.End,
+		Width: n.originalWidth,
+		Data:  p.data,
+	}
+	// empty fields are left nil, as collect does
+	if len(p.set) != 0 {
+		p.Set = p.set
+	}
+	if len(p.counts) != 0 {
+		p.Counts = p.counts
+	}
+}
@@ -0,0 +1,60 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSamplePooled(t *testing.T) {
+	for _, setters := range [][]Option{nil, {WithLeafCounts()}} {
+		tree := sharedNamespaceTree(t, setters...)
+		opts := tree.Options()
+		indices := []uint{0, 5, 15, 16, 22, 31}
+
+		expected, err := tree.Sample(indices)
+		assert.NoError(t, err)
+		// proofs are filled from released buffers on the second round
+		for round := 0; round < 2; round++ {
+			pooled, err := tree.SamplePooled(indices)
+			assert.NoError(t, err)
+			for i, proof := range pooled {
+				assert.Equal(t, expected[i], proof.Proof)
+				assert.True(t, Verify(tree.Root(), &opts, proof.Proof))
+				raw, err := proof.MarshalCBOR()
+				assert.NoError(t, err)
+				expectedRaw, err := expected[i].MarshalCBOR()
+				assert.NoError(t, err)
+				assert.Equal(t, expectedRaw, raw)
+			}
+			for _, proof := range pooled {
+				proof.Release()
+			}
+		}
+	}
+}
+
+func TestProveLeafPooled(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	first, err := tree.ProveLeafPooled(1)
+	assert.NoError(t, err)
+	second, err := tree.ProveLeafPooled(2)
+	assert.NoError(t, err)
+
+	// appending to the data of one leaf does not affect the tree
+	first.Data[0] = append(first.Data[0], 1)
+	assert.True(t, Verify(tree.Root(), nil, second.Proof))
+	first.Release()
+	second.Release()
+
+	// a nil proof can be released
+	var missing *PooledProof
+	missing.Release()
+
+	_, err = tree.ProveLeafPooled(32)
+	assert.Error(t, err)
+	_, err = tree.SamplePooled([]uint{0, 32})
+	assert.Error(t, err)
+	_, err = NewNCMT().ProveLeafPooled(0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package ncmt
+
+import (
+	"crypto/sha256"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// Presets bundle parameters that are tested together. Each preset overwrites
+// every option it covers, so it should be passed before any other Option that
+// is meant to override it.
+var (
+	// PresetSmallBlock uses the default parameters: batches of 4 symbols,
+	// 8 byte namespaces, sha256, and the GF(2^8) codec. Supports up to 128
+	// leaves, in any power of 2.
+	PresetSmallBlock Option = preset(Options{
+		UniformParityNamespace: true,
+		BatchSize:              4,
+		NamespaceSize:          namespace.IDSize(8),
+		FreshHash:              sha256.New,
+		HashID:                 HashSHA256,
+		Codec:                  RSFG8{},
+		CommitmentVersion:      CommitmentV0,
+		Parallelism:            1,
+	})
+
+	// PresetLargeBlock batches 8 symbols per node, which halves the depth of
+	// the tree compared to PresetSmallBlock, and erasures layers in segments
+	// of 64 symbols, so that the GF(2^8) codec supports any number of leaves.
+	// Leaf counts must be a power of 4, of at least 16.
+	PresetLargeBlock Option = preset(Options{
+		UniformParityNamespace: true,
+		BatchSize:              8,
+		NamespaceSize:          namespace.IDSize(8),
+		FreshHash:              sha256.New,
+		HashID:                 HashSHA256,
+		Codec:                  RSFG8{},
+		CommitmentVersion:      CommitmentV0,
+		SegmentSize:            64,
+		Parallelism:            1,
+	})
+
+	// PresetLightClientCompat uses the domain separated CommitmentV1 scheme
+	// and commits to leaf counts, so that light clients can check how many
+	// leaves a namespace has. Proofs are larger than with the other presets,
+	// as they carry the counts of their siblings.
+	PresetLightClientCompat Option = preset(Options{
+		UniformParityNamespace: true,
+		BatchSize:              4,
+		NamespaceSize:          namespace.IDSize(8),
+		FreshHash:              sha256.New,
+		HashID:                 HashSHA256,
+		Codec:                  RSFG8{},
+		CommitmentVersion:      CommitmentV1,
+		LeafCounts:             true,
+		Parallelism:            1,
+	})
+)
+
+// preset returns an Option that replaces the options with p
+func preset(p Options) Option {
+	return func(o *Options) {
+		*o = p
+	}
+}
@@ -0,0 +1,39 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresets(t *testing.T) {
+	presets := []struct {
+		name      string
+		preset    Option
+		leafCount int
+	}{
+		{"small block", PresetSmallBlock, 128},
+		{"large block", PresetLargeBlock, 64},
+		// more leaves than the codec supports in a single codeword
+		{"large block segmented", PresetLargeBlock, 1024},
+		{"light client compat", PresetLightClientCompat, 128},
+	}
+	for _, p := range presets {
+		tree := NewNCMT(p.preset)
+		for _, d := range mockData(p.leafCount, 32) {
+			assert.NoError(t, tree.Push(d), p.name)
+		}
+		_, err := tree.Build()
+		assert.NoError(t, err, p.name)
+		assert.NoError(t, CheckInvariants(tree), p.name)
+		assert.NoError(t, CheckCodec(tree.opts.Codec), p.name)
+	}
+
+	// the small block preset matches the defaults
+	assert.Equal(t, NewNCMT().Options().BatchSize, NewNCMT(PresetSmallBlock).Options().BatchSize)
+
+	// later options override a preset
+	tree := NewNCMT(PresetLightClientCompat, WithCommitmentVersion(CommitmentV0))
+	assert.Equal(t, CommitmentV0, tree.Options().CommitmentVersion)
+	assert.True(t, tree.Options().LeafCounts)
+}
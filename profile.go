@@ -0,0 +1,27 @@
+package ncmt
+
+import "time"
+
+// profileStage identifies the part of the Build pipeline being timed
+type profileStage string
+
+const (
+	encodeStage profileStage = "encode"
+	hashStage   profileStage = "hash"
+)
+
+// profileBuckets are the upper bounds of each histogram bucket. Durations
+// greater than the last bound are counted in an additional overflow bucket.
+var profileBuckets = []time.Duration{
+	time.Microsecond,
+	4 * time.Microsecond,
+	16 * time.Microsecond,
+	64 * time.Microsecond,
+	256 * time.Microsecond,
+	time.Millisecond,
+	4 * time.Millisecond,
+	16 * time.Millisecond,
+	64 * time.Millisecond,
+	256 * time.Millisecond,
+	time.Second,
+}
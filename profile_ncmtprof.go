@@ -0,0 +1,132 @@
+//go:build ncmtprof
+
+package ncmt
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// profiler accumulates timings for every Build performed while the package is
+// compiled with the ncmtprof tag
+var profiler = struct {
+	sync.Mutex
+	histograms map[profileKey]*histogram
+}{histograms: make(map[profileKey]*histogram)}
+
+type profileKey struct {
+	stage profileStage
+	layer int
+}
+
+type histogram struct {
+	count    uint64
+	total    time.Duration
+	min, max time.Duration
+	buckets  []uint64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.total += d
+	idx := sort.Search(len(profileBuckets), func(i int) bool {
+		return d <= profileBuckets[i]
+	})
+	h.buckets[idx]++
+}
+
+// profileTimer measures a single stage of a single layer
+type profileTimer struct {
+	key   profileKey
+	start time.Time
+}
+
+func startProfile(stage profileStage, layer int) profileTimer {
+	return profileTimer{
+		key:   profileKey{stage: stage, layer: layer},
+		start: time.Now(),
+	}
+}
+
+func (t profileTimer) stop() {
+	elapsed := time.Since(t.start)
+	profiler.Lock()
+	defer profiler.Unlock()
+	h, found := profiler.histograms[t.key]
+	if !found {
+		h = &histogram{buckets: make([]uint64, len(profileBuckets)+1)}
+		profiler.histograms[t.key] = h
+	}
+	h.observe(elapsed)
+}
+
+// WriteProfile writes the encode and hash timings of every layer recorded
+// since the last ResetProfile as CSV. Each row is a single stage of a single
+// layer, where layer 0 is the leaf layer. Bucket columns hold the number of
+// observations less than or equal to the bound, but greater than the previous
+// bound.
+func WriteProfile(w io.Writer) error {
+	profiler.Lock()
+	defer profiler.Unlock()
+
+	header := []string{"stage", "layer", "count", "total_ns", "min_ns", "max_ns"}
+	for _, bound := range profileBuckets {
+		header = append(header, fmt.Sprintf("le_%s", bound))
+	}
+	header = append(header, "le_inf")
+
+	keys := make([]profileKey, 0, len(profiler.histograms))
+	for key := range profiler.histograms {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].stage != keys[j].stage {
+			return keys[i].stage < keys[j].stage
+		}
+		return keys[i].layer < keys[j].layer
+	})
+
+	cw := csv.NewWriter(w)
+	err := cw.Write(header)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		h := profiler.histograms[key]
+		row := []string{
+			string(key.stage),
+			strconv.Itoa(key.layer),
+			strconv.FormatUint(h.count, 10),
+			strconv.FormatInt(h.total.Nanoseconds(), 10),
+			strconv.FormatInt(h.min.Nanoseconds(), 10),
+			strconv.FormatInt(h.max.Nanoseconds(), 10),
+		}
+		for _, count := range h.buckets {
+			row = append(row, strconv.FormatUint(count, 10))
+		}
+		err = cw.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ResetProfile discards all recorded timings
+func ResetProfile() {
+	profiler.Lock()
+	defer profiler.Unlock()
+	profiler.histograms = make(map[profileKey]*histogram)
+}
@@ -0,0 +1,30 @@
+//go:build ncmtprof
+
+package ncmt
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteProfile(t *testing.T) {
+	ResetProfile()
+	tree := mockTree(16, 4, t)
+
+	buf := &bytes.Buffer{}
+	err := WriteProfile(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one header, then an encode and hash row for every layer that was extended
+	assert.Equal(t, 1+2*len(tree.layers), len(rows))
+	assert.Equal(t, []string{"encode", "0", "1"}, rows[1][:3])
+	assert.Equal(t, []string{"hash", "0", "1"}, rows[1+len(tree.layers)][:3])
+}
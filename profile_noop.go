@@ -0,0 +1,23 @@
+//go:build !ncmtprof
+
+package ncmt
+
+import (
+	"errors"
+	"io"
+)
+
+// profileTimer is a no-op unless compiled with the ncmtprof tag
+type profileTimer struct{}
+
+func startProfile(profileStage, int) profileTimer { return profileTimer{} }
+
+func (profileTimer) stop() {}
+
+// WriteProfile returns an error unless compiled with the ncmtprof tag
+func WriteProfile(w io.Writer) error {
+	return errors.New("profiling disabled: build with -tags ncmtprof")
+}
+
+// ResetProfile is a no-op unless compiled with the ncmtprof tag
+func ResetProfile() {}
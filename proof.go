@@ -1,63 +1,503 @@
 package ncmt
 
-// Proof describes the data needed to verify inclusion of some data in a NCMT
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/lazyledger/nmt/namespace"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Proof describes the data needed to verify inclusion of some data in a NCMT.
+// Positions are indexes into the extended leaf layer, where [0, Width) are the
+// original leaves and [Width, 2*Width) are their erasures.
 type Proof struct {
-	Set    [][]byte
-	Root   []byte
-	Index  uint
-	Leaves uint
+	// Start and End are the range of proven leaves [Start, End)
+	Start uint `cbor:"start"`
+	End   uint `cbor:"end"`
+	// Width is the number of original leaves in the tree
+	Width uint `cbor:"width"`
+	// Data holds the namespace prefixed data of every proven leaf
+	Data [][]byte `cbor:"data"`
+	// Set holds the hashes of every sibling needed to recompute the root. It
+	// is ordered by layer from the leaves up, then by parent, then by the
+	// position of the sibling in its batch.
+	Set [][]byte `cbor:"set"`
+	// Counts holds the min and max leaf counts of every original node in Set
+	// above the leaf layer, in the same order. It is only used when leaf counts
+	// are committed.
+	Counts [][2]uint64 `cbor:"counts"`
+	// NamespaceID is only set for namespace proofs. If it is set, the proof
+	// also shows that Data holds every leaf of the namespace, or, if none of
+	// the data belongs to the namespace, that the namespace is absent.
+	NamespaceID namespace.ID `cbor:"namespace_id"`
+	// LeafHashes holds the hashes of the proven leaves instead of Data in
+	// HashesOnly proofs. It is omitted from the encoding of proofs with data,
+	// so that their encoding does not change.
+	LeafHashes [][]byte `cbor:"leaf_hashes,omitempty"`
 }
 
-// return a simpler more direct proof and serialize later
-// just use a RS decoder to 'peel' back layers
+// Absence returns true if p is a namespace proof showing that its namespace is
+// not in the tree
+func (p Proof) Absence() bool {
+	if len(p.NamespaceID) == 0 {
+		return false
+	}
+	// leaf hashes are prefixed with the namespace of their leaf, like data
+	for _, data := range append(append([][]byte{}, p.Data...), p.LeafHashes...) {
+		if bytes.HasPrefix(data, p.NamespaceID) {
+			return false
+		}
+	}
+	return true
+}
 
-// func Verify(h hash.Hash, p Proof) bool {
+// HashesOnly returns true if p proves the hashes of its leaves instead of
+// their data
+func (p Proof) HashesOnly() bool {
+	return len(p.LeafHashes) != 0
+}
 
-// }
+/////////////////////////////////////////
+//  Generating proofs
+///////////////////////////////////////
 
-// func (n *NCMT) ProveNamespace(nID namespace.ID) (Proof, error) {
-// 	// find the namespace or return an error
-// 	found, start, end := n.foundInRange(nID)
-// 	if !found {
-// 		return Proof{}, fmt.Errorf("names not found in tree: %s", string(nID))
-// 	}
-// 	// build proof
-// 	return Proof{}, nil
-// }
+// ProveLeaf creates a proof for a single leaf of the extended leaf layer, so
+// idx can refer to an original leaf or to an erasured leaf
+func (n *NCMT) ProveLeaf(idx uint) (Proof, error) {
+	return n.ProveRange(idx, idx+1)
+}
 
+// ProveRange creates a proof for the leaves [start, end) of the extended leaf
+// layer. The range must fall entirely within the original leaves, or entirely
+// within the erasured leaves.
 func (n *NCMT) ProveRange(start, end uint) (Proof, error) {
-	// check that the range is valid
-	if end < uint(len(n.leaves)) && start <= end {
-
-	}
-	return Proof{}, nil
-}
-
-// // planProofRange determines the nodes that are needed to prove inclusion of a
-// // given range
-// func (n *NCMT) planProofRange(start, end uint) {
-// 	//
-// 	return nil
-// }
-
-// TODO: keep erasured leaves separate
-
-// func (n *NCMT) ProveLeaf(idx uint) (Proof, error) {
-// 	// check range
-// 	if idx > uint(len(n.leaves)) {
-// 		return Proof{}, fmt.Errorf(
-// 			"leaf out of range: max range %d, id given %d",
-// 			len(n.leaves),
-// 			idx,
-// 		)
-// 	}
-
-// 	// iterate through each layer
-// 	heritage := make([]node, len(n.layers))
-// 	nextIndx := idx
-// 	for i, l := range n.layers {
-// 		heritage[i] = l[nextIndx]
-// 		nextIndx = nextIndx / 2
-// 	}
-// 	// expand each node into the hashes
-// }
+	return n.ProveRangeContext(context.Background(), start, end)
+}
+
+// ProveRangeContext performs ProveRange, creating any tracing spans as
+// children of ctx. If ctx ends before the proof is complete, a *DeadlineError
+// is returned.
+func (n *NCMT) ProveRangeContext(ctx context.Context, start, end uint) (proof Proof, err error) {
+	_, span := startSpan(ctx, n.opts.Tracer, "ncmt.ProveRange",
+		attribute.Int64("ncmt.start", int64(start)),
+		attribute.Int64("ncmt.end", int64(end)),
+	)
+	defer func() {
+		span.SetAttributes(proofAttrs(proof)...)
+		endSpan(span, err)
+	}()
+	plan, err := n.rangePlan(start, end)
+	if err != nil {
+		return Proof{}, err
+	}
+	return n.planAndCollect(ctx, plan)
+}
+
+// rangePlan starts the plan of a proof of the leaves [start, end)
+func (n *NCMT) rangePlan(start, end uint) (ProofPlan, error) {
+	if len(n.layers) == 0 {
+		return ProofPlan{}, errors.New("tree must be built before creating proofs")
+	}
+	err := checkProofRange(start, end, n.originalWidth)
+	if err != nil {
+		return ProofPlan{}, err
+	}
+	return n.newPlan(start, end), nil
+}
+
+// ProveNamespace creates a proof for every leaf of nID. If nID is not in the
+// tree, an absence proof is created instead, which proves the single leaf
+// where nID would have been, showing that its neighbours belong to other
+// namespaces.
+func (n *NCMT) ProveNamespace(nID namespace.ID) (Proof, error) {
+	return n.ProveNamespaceContext(context.Background(), nID)
+}
+
+// ProveNamespaceContext performs ProveNamespace, creating any tracing spans as
+// children of ctx. If ctx ends before the proof is complete, a *DeadlineError
+// is returned.
+func (n *NCMT) ProveNamespaceContext(ctx context.Context, nID namespace.ID) (proof Proof, err error) {
+	_, span := startSpan(ctx, n.opts.Tracer, "ncmt.ProveNamespace", namespaceAttr(nID))
+	defer func() {
+		span.SetAttributes(proofAttrs(proof)...)
+		span.SetAttributes(attribute.Bool("ncmt.proof.absence", proof.Absence()))
+		endSpan(span, err)
+	}()
+	plan, err := n.namespacePlan(nID)
+	if err != nil {
+		return Proof{}, err
+	}
+	return n.planAndCollect(ctx, plan)
+}
+
+// namespacePlan starts the plan of a proof of every leaf of nID, or of its
+// absence
+func (n *NCMT) namespacePlan(nID namespace.ID) (ProofPlan, error) {
+	if len(n.layers) == 0 {
+		return ProofPlan{}, errors.New("tree must be built before creating proofs")
+	}
+	if nID.Size() != n.opts.NamespaceSize {
+		return ProofPlan{}, fmt.Errorf(
+			"invalid namespace: expected size %d, received size %d",
+			n.opts.NamespaceSize,
+			nID.Size(),
+		)
+	}
+	found, start, end := n.foundInRange(nID)
+	if !found {
+		// prove the first leaf greater than nID, or the last leaf if there is
+		// no such leaf
+		originals := n.originalLeaves()
+		start = uint(sort.Search(len(originals), func(i int) bool {
+			return nID.Less(originals[i].min)
+		}))
+		if start == uint(len(originals)) {
+			start--
+		}
+		end = start + 1
+	}
+	plan := n.newPlan(start, end)
+	plan.NamespaceID = append(namespace.ID{}, nID...)
+	return plan, nil
+}
+
+// prove collects the data of the leaves [start, end) and the sibling hashes
+// required to recompute the root. Assumes the range is valid.
+func (n *NCMT) prove(start, end uint) (Proof, error) {
+	return n.planAndCollect(context.Background(), n.newPlan(start, end))
+}
+
+// symbol returns the node at pos of the extended layer at level, where level 0
+// is the leaf layer
+func (n *NCMT) symbol(level int, pos uint) node {
+	if level == 0 {
+		return n.leaves[pos].node
+	}
+	originals := n.layers[level-1]
+	if pos < uint(len(originals)) {
+		return originals[pos]
+	}
+	return n.extendedLayers[level-1][pos-uint(len(originals))]
+}
+
+/////////////////////////////////////////
+//  Verifying proofs
+///////////////////////////////////////
+
+// Verify checks that p is valid for root, using the hash function, batch size,
+// namespace size and commitment parameters of opts. If opts is nil, the
+// default options are used. Namespace proofs are also checked for
+// completeness, or absence.
+func Verify(root []byte, opts *Options, p Proof) bool {
+	return VerifyContext(context.Background(), root, opts, p)
+}
+
+// VerifyContext performs Verify, creating any tracing spans as children of ctx
+func VerifyContext(ctx context.Context, root []byte, opts *Options, p Proof) bool {
+	if opts == nil {
+		opts = NewNCMT().opts
+	}
+	_, span := startSpan(ctx, opts.Tracer, "ncmt.Verify", proofAttrs(p)...)
+	if len(p.NamespaceID) != 0 {
+		span.SetAttributes(namespaceAttr(p.NamespaceID))
+	}
+	err := verify(root, opts, p)
+	span.SetAttributes(attribute.Bool("ncmt.valid", err == nil))
+	endSpan(span, err)
+	return err == nil
+}
+
+// Verifier returns a VerifyFunc that calls Verify using opts
+func Verifier(opts *Options) VerifyFunc {
+	return func(root []byte, p Proof) bool {
+		return Verify(root, opts, p)
+	}
+}
+
+// VerifyWithHeader checks that p is valid for the root of a tree described by
+// header, using the hash function of the header, or the default if it has
+// none. The header's shape must be consistent, and match the width of p. If
+// the header has a namespace filter, namespace proofs must also be consistent
+// with it, and absence proofs of namespaces the filter excludes are accepted
+// without checking their siblings.
+func VerifyWithHeader(header TreeHeader, p Proof) bool {
+	if header.Validate() != nil {
+		return false
+	}
+	if header.OriginalWidth != 0 && p.Width != header.OriginalWidth {
+		return false
+	}
+	if len(p.NamespaceID) != 0 && header.ExcludesNamespace(p.NamespaceID) {
+		return p.Absence()
+	}
+	opts := NewNCMT(header.ApplyTo()).opts
+	return Verify(header.Root, opts, p)
+}
+
+// verify returns a description of the first problem found with p
+func verify(root []byte, opts *Options, p Proof) error {
+	return verifySides(root, opts, p, true, true)
+}
+
+// verifySides verifies p, only checking that the namespace is absent to the
+// left or right of a namespace proof if left or right are set. This allows a
+// namespace proof to be split into pieces that are each only complete on the
+// sides they share with the namespace.
+func verifySides(root []byte, opts *Options, p Proof, left, right bool) error {
+	nsSize := int(opts.NamespaceSize)
+	err := checkBatchSize(opts.BatchSize)
+	if err != nil {
+		return err
+	}
+	err = opts.checkHash()
+	if err != nil {
+		return err
+	}
+	err = checkProofRange(p.Start, p.End, p.Width)
+	if err != nil {
+		return err
+	}
+	if p.HashesOnly() {
+		if len(p.Data) != 0 {
+			return errors.New("proof holds both data and leaf hashes")
+		}
+		if uint(len(p.LeafHashes)) != p.End-p.Start {
+			return fmt.Errorf("expected %d leaf hashes, received %d", p.End-p.Start, len(p.LeafHashes))
+		}
+	} else if uint(len(p.Data)) != p.End-p.Start {
+		return fmt.Errorf("expected %d leaves of data, received %d", p.End-p.Start, len(p.Data))
+	}
+	isNamespaceProof := len(p.NamespaceID) != 0
+	if isNamespaceProof {
+		if len(p.NamespaceID) != nsSize {
+			return errors.New("invalid namespace size")
+		}
+		if p.End > p.Width {
+			return errors.New("namespace proofs can only include original leaves")
+		}
+	}
+	absence := p.Absence()
+
+	scheme := hashScheme{
+		version:      opts.CommitmentVersion,
+		commitCounts: opts.LeafCounts,
+		salt:         opts.Salt,
+	}
+
+	// hash the proven leaves
+	known := rangePositions(p.Start, p.End)
+	values := make(map[uint]node, len(known))
+	for i, raw := range p.Data {
+		data, err := ParseNamespacedData(opts.NamespaceSize, raw)
+		if err != nil {
+			return fmt.Errorf("invalid leaf data: %s", err)
+		}
+		if isNamespaceProof {
+			// presence proofs must only contain the namespace, and absence
+			// proofs must not contain it at all
+			if data.ID.Equal(p.NamespaceID) == absence {
+				return errors.New("leaf data does not match the proven namespace")
+			}
+		}
+		pos := known[i]
+		lf := newLeaf(opts.FreshHash(), data, scheme)
+		if pos >= p.Width {
+			lf.node.parity = true
+			lf.node.minCount, lf.node.maxCount = 0, 0
+		}
+		values[pos] = lf.node
+	}
+	for i, hash := range p.LeafHashes {
+		pos := known[i]
+		value, err := siblingNode(0, pos, p.Width, hash, nsSize)
+		if err != nil {
+			return fmt.Errorf("invalid leaf hash: %s", err)
+		}
+		if isNamespaceProof && value.min.Equal(p.NamespaceID) == absence {
+			return errors.New("leaf hash does not match the proven namespace")
+		}
+		values[pos] = value
+	}
+
+	// siblings to the left of the proven range must only contain lesser
+	// namespaces, and siblings to the right greater
+	checkSibling := func(child, lowest, highest uint, sibling node) error {
+		if !isNamespaceProof || sibling.parity {
+			return nil
+		}
+		if left && child < lowest && !sibling.max.Less(p.NamespaceID) {
+			return errors.New("namespace found to the left of the proven range")
+		}
+		if right && child > highest && !p.NamespaceID.Less(sibling.min) {
+			return errors.New("namespace found to the right of the proven range")
+		}
+		return nil
+	}
+	return climb(root, opts, scheme, 0, p.Width, known, values, p.Set, p.Counts, checkSibling)
+}
+
+// climb recomputes the root from the known nodes of the extended layer at
+// level, whose original width is width, using the sibling hashes in set and
+// the leaf counts in counts. checkSibling is called for every sibling.
+func climb(
+	root []byte,
+	opts *Options,
+	scheme hashScheme,
+	level int,
+	width uint,
+	known []uint,
+	values map[uint]node,
+	set [][]byte,
+	counts [][2]uint64,
+	checkSibling func(child, lowest, highest uint, sibling node) error,
+) error {
+	batchSize := uint(opts.BatchSize / 2)
+	nsSize := int(opts.NamespaceSize)
+	for ; width > 1; level++ {
+		if width%batchSize != 0 {
+			return fmt.Errorf("width %d is not divisible by the batch size", width)
+		}
+		parents := parentPositions(known, width, batchSize)
+		lowest, highest := known[0], known[len(known)-1]
+		next := make(map[uint]node, len(parents))
+		for _, parent := range parents {
+			positions := batchPositions(parent, width, batchSize)
+			children := make([]node, len(positions))
+			for i, child := range positions {
+				if value, has := values[child]; has {
+					children[i] = value
+					continue
+				}
+				if len(set) == 0 {
+					return errors.New("proof is missing sibling hashes")
+				}
+				sibling, err := siblingNode(level, child, width, set[0], nsSize)
+				if err != nil {
+					return err
+				}
+				set = set[1:]
+				if opts.LeafCounts && level > 0 && !sibling.parity {
+					if len(counts) == 0 {
+						return errors.New("proof is missing leaf counts")
+					}
+					sibling.minCount, sibling.maxCount = counts[0][0], counts[0][1]
+					counts = counts[1:]
+				}
+				err = checkSibling(child, lowest, highest, sibling)
+				if err != nil {
+					return err
+				}
+				children[i] = sibling
+			}
+			next[parent] = newNode(opts.FreshHash(), children, scheme)
+		}
+		values = next
+		known = parents
+		width = width / batchSize
+	}
+	if len(set) != 0 || len(counts) != 0 {
+		return errors.New("proof contains unused hashes")
+	}
+	computed, has := values[0]
+	if !has || len(known) != 1 {
+		return errors.New("proof does not lead to a single root")
+	}
+	if !bytes.Equal(computed.hash, root) {
+		return errors.New("computed root does not match")
+	}
+	return nil
+}
+
+// siblingNode interprets a sibling hash at pos of the extended layer at level
+func siblingNode(level int, pos, width uint, hash []byte, nsSize int) (node, error) {
+	if pos >= width {
+		return node{hash: hash, parity: true}, nil
+	}
+	if level == 0 {
+		if len(hash) < nsSize {
+			return node{}, errors.New("sibling leaf hash is too short")
+		}
+		id := namespace.ID(hash[:nsSize])
+		return node{hash: hash, min: id, max: id, minCount: 1, maxCount: 1}, nil
+	}
+	if len(hash) < 2*nsSize {
+		return node{}, errors.New("sibling node hash is too short")
+	}
+	return node{
+		hash: hash,
+		min:  namespace.ID(hash[:nsSize]),
+		max:  namespace.ID(hash[nsSize : 2*nsSize]),
+	}, nil
+}
+
+/////////////////////////////////////////
+//  Navigating the extended layers
+///////////////////////////////////////
+
+// checkProofRange ensures that [start, end) is a non empty range of the
+// extended leaf layer that does not mix original and erasured leaves
+func checkProofRange(start, end, width uint) error {
+	switch {
+	case start >= end:
+		return fmt.Errorf("invalid range [%d, %d)", start, end)
+	case end > 2*width:
+		return fmt.Errorf("range [%d, %d) is outside of the %d extended leaves", start, end, 2*width)
+	case start < width && end > width:
+		return fmt.Errorf("range [%d, %d) includes both original and erasured leaves", start, end)
+	}
+	return nil
+}
+
+// rangePositions lists every position in [start, end)
+func rangePositions(start, end uint) []uint {
+	positions := make([]uint, 0, end-start)
+	for i := start; i < end; i++ {
+		positions = append(positions, i)
+	}
+	return positions
+}
+
+// parentPositions returns the sorted and unique positions of the parents of
+// the sorted positions in an extended layer of the given original width.
+// Parents are always original nodes of the next layer.
+func parentPositions(positions []uint, width, batchSize uint) []uint {
+	parents := make([]uint, 0, len(positions))
+	for _, pos := range positions {
+		if pos >= width {
+			pos -= width
+		}
+		parents = append(parents, pos/batchSize)
+	}
+	// positions from both halves of the layer can share a parent
+	sort.Slice(parents, func(i, j int) bool { return parents[i] < parents[j] })
+	unique := parents[:0]
+	for i, parent := range parents {
+		if i == 0 || parents[i-1] != parent {
+			unique = append(unique, parent)
+		}
+	}
+	return unique
+}
+
+// batchPositions returns the positions of the children of parent in an
+// extended layer of the given original width, in the order they are hashed:
+// the original nodes followed by their erasures
+func batchPositions(parent, width, batchSize uint) []uint {
+	positions := make([]uint, 0, 2*batchSize)
+	for i := parent * batchSize; i < (parent+1)*batchSize; i++ {
+		positions = append(positions, i)
+	}
+	for i := parent * batchSize; i < (parent+1)*batchSize; i++ {
+		positions = append(positions, width+i)
+	}
+	return positions
+}
+
+func containsPosition(sorted []uint, pos uint) bool {
+	i := sort.Search(len(sorted), func(i int) bool { return sorted[i] >= pos })
+	return i < len(sorted) && sorted[i] == pos
+}
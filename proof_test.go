@@ -1 +1,181 @@
 package ncmt
+
+import (
+	"testing"
+
+	"github.com/lazyledger/nmt/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+// sharedNamespaceTree builds a tree of 16 leaves where every three leaves
+// share an even namespace, starting at 2
+func sharedNamespaceTree(t *testing.T, setters ...Option) *NCMT {
+	tree := NewNCMT(setters...)
+	for i, d := range mockData(16, 8) {
+		id := mockID(2*(i/3) + 2)
+		err := tree.Push(namespace.NewPrefixedData(id.Size(), append(id, d.Data()...)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := tree.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestProveNamespace(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	root := tree.Root()
+
+	proof, err := tree.ProveNamespace(mockID(4))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), proof.Start)
+	assert.Equal(t, uint(6), proof.End)
+	assert.False(t, proof.Absence())
+	assert.True(t, Verify(root, nil, proof))
+
+	// the last namespace only has a single leaf
+	proof, err = tree.ProveNamespace(mockID(12))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(15), proof.Start)
+	assert.Equal(t, uint(16), proof.End)
+	assert.True(t, Verify(root, nil, proof))
+
+	// absent namespaces below, between and above those in the tree
+	for _, id := range []int{1, 5, 255} {
+		proof, err := tree.ProveNamespace(mockID(id))
+		assert.NoError(t, err)
+		assert.True(t, proof.Absence())
+		assert.True(t, Verify(root, nil, proof), id)
+	}
+
+	// leaving a leaf of the namespace out of the proof must fail
+	full, err := tree.ProveNamespace(mockID(4))
+	assert.NoError(t, err)
+	partial, err := tree.ProveRange(3, 5)
+	assert.NoError(t, err)
+	partial.NamespaceID = full.NamespaceID
+	assert.True(t, Verify(root, nil, Proof{
+		Start: partial.Start, End: partial.End, Width: partial.Width,
+		Data: partial.Data, Set: partial.Set,
+	}))
+	assert.False(t, Verify(root, nil, partial))
+
+	// claiming absence of a present namespace must fail
+	absence, err := tree.ProveRange(6, 7)
+	assert.NoError(t, err)
+	absence.NamespaceID = mockID(5)
+	assert.True(t, Verify(root, nil, absence))
+	absence.NamespaceID = mockID(6)
+	assert.False(t, Verify(root, nil, absence))
+
+	_, err = tree.ProveNamespace(namespace.ID{1})
+	assert.Error(t, err)
+	_, err = NewNCMT().ProveNamespace(mockID(1))
+	assert.Error(t, err)
+}
+
+func TestProveRange(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	root := tree.Root()
+
+	for _, r := range [][2]uint{{0, 16}, {0, 1}, {5, 11}, {16, 17}, {20, 32}, {31, 32}} {
+		proof, err := tree.ProveRange(r[0], r[1])
+		assert.NoError(t, err)
+		assert.True(t, Verify(root, nil, proof), r)
+	}
+
+	// every original and erasured leaf can be proven on its own
+	for i := uint(0); i < 32; i++ {
+		proof, err := tree.ProveLeaf(i)
+		assert.NoError(t, err)
+		assert.True(t, Verify(root, nil, proof), i)
+	}
+
+	for _, r := range [][2]uint{{3, 3}, {4, 2}, {15, 17}, {30, 33}} {
+		_, err := tree.ProveRange(r[0], r[1])
+		assert.Error(t, err, r)
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	root := tree.Root()
+	proof, err := tree.ProveRange(2, 4)
+	assert.NoError(t, err)
+
+	copyProof := func() Proof {
+		cp := proof
+		cp.Data = make([][]byte, len(proof.Data))
+		for i, d := range proof.Data {
+			cp.Data[i] = append([]byte{}, d...)
+		}
+		cp.Set = make([][]byte, len(proof.Set))
+		for i, s := range proof.Set {
+			cp.Set[i] = append([]byte{}, s...)
+		}
+		return cp
+	}
+
+	tampered := copyProof()
+	tampered.Data[0][len(tampered.Data[0])-1]++
+	assert.False(t, Verify(root, nil, tampered))
+
+	tampered = copyProof()
+	tampered.Set[len(tampered.Set)-1][0]++
+	assert.False(t, Verify(root, nil, tampered))
+
+	tampered = copyProof()
+	tampered.Set = tampered.Set[1:]
+	assert.False(t, Verify(root, nil, tampered))
+
+	tampered = copyProof()
+	tampered.Set = append(tampered.Set, []byte{1})
+	assert.False(t, Verify(root, nil, tampered))
+
+	tampered = copyProof()
+	tampered.Start, tampered.End = 3, 5
+	assert.False(t, Verify(root, nil, tampered))
+
+	assert.True(t, Verify(root, nil, copyProof()))
+	assert.False(t, Verify(tree.layers[0][0].hash, nil, copyProof()))
+}
+
+func TestProofOptions(t *testing.T) {
+	setups := [][]Option{
+		{WithLeafCounts()},
+		{WithCommitmentVersion(CommitmentV1)},
+		{WithCommitmentVersion(CommitmentV1), WithLeafCounts(), WithSalt([]byte("salt"))},
+		{WithSalt([]byte("salt")), func(o *Options) { o.BatchSize = 8 }},
+	}
+	for i, setters := range setups {
+		tree := sharedNamespaceTree(t, setters...)
+		opts := tree.Options()
+		header, err := tree.Header()
+		assert.NoError(t, err)
+
+		proof, err := tree.ProveNamespace(mockID(6))
+		assert.NoError(t, err)
+		assert.True(t, Verify(tree.Root(), &opts, proof), i)
+		assert.True(t, Verifier(&opts)(tree.Root(), proof), i)
+		assert.True(t, VerifyWithHeader(header, proof), i)
+		// the default options do not match the tree
+		assert.False(t, Verify(tree.Root(), nil, proof), i)
+
+		proof, err = tree.ProveLeaf(20)
+		assert.NoError(t, err)
+		assert.True(t, VerifyWithHeader(header, proof), i)
+	}
+
+	// tampering with the committed counts must fail
+	tree := sharedNamespaceTree(t, WithLeafCounts())
+	proof, err := tree.ProveLeaf(0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, proof.Counts)
+	opts := tree.Options()
+	assert.True(t, Verify(tree.Root(), &opts, proof))
+	proof.Counts[0][0]++
+	assert.False(t, Verify(tree.Root(), &opts, proof))
+}
@@ -0,0 +1,105 @@
+package ncmt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// ErrPayloadPruned is matched by the errors of requests that need the payload
+// of a leaf that has been pruned
+var ErrPayloadPruned = errors.New("leaf payload has been pruned")
+
+// ProofMode selects what a proof holds for each of its proven leaves
+type ProofMode uint8
+
+const (
+	// WithData proofs hold the namespace prefixed data of their leaves
+	WithData ProofMode = iota
+	// HashesOnly proofs hold the hashes of their leaves, which still prove the
+	// position and namespace of each leaf, but not its data
+	HashesOnly
+)
+
+// PrunePayloads drops the payloads of the leaves [start, end) of the extended
+// leaf layer of a built tree, keeping their namespaces and hashes. Proofs of
+// pruned leaves can still be created in HashesOnly mode, but anything else
+// that needs their data fails with ErrPayloadPruned.
+func (n *NCMT) PrunePayloads(start, end uint) error {
+	if len(n.layers) == 0 {
+		return errors.New("tree must be built before pruning payloads")
+	}
+	if start >= end || end > uint(len(n.leaves)) {
+		return fmt.Errorf("invalid range [%d, %d) of the %d leaves", start, end, len(n.leaves))
+	}
+	for i := start; i < end; i++ {
+		lf := &n.leaves[i]
+		lf.data = NamespacedData{ID: append(namespace.ID{}, lf.data.NamespaceID()...)}
+		lf.pruned = true
+	}
+	return nil
+}
+
+// PrunedLeaves returns the positions of the extended leaf layer in
+// [start, end) whose payloads have been pruned
+func (n *NCMT) PrunedLeaves(start, end uint) []uint {
+	if end > uint(len(n.leaves)) {
+		end = uint(len(n.leaves))
+	}
+	var pruned []uint
+	for i := start; i < end; i++ {
+		if n.leaves[i].pruned {
+			pruned = append(pruned, i)
+		}
+	}
+	return pruned
+}
+
+// checkPayloads returns an error matching ErrPayloadPruned if any of the
+// leaves [start, end) has been pruned
+func (n *NCMT) checkPayloads(start, end uint) error {
+	pruned := n.PrunedLeaves(start, end)
+	if len(pruned) != 0 {
+		return fmt.Errorf("leaf %d: %w", pruned[0], ErrPayloadPruned)
+	}
+	return nil
+}
+
+// ProveRangeMode performs ProveRange, creating a proof in mode. If mode is
+// WithData and some of the proven leaves have been pruned, a HashesOnly proof
+// is created instead, and the positions of the pruned leaves are returned.
+func (n *NCMT) ProveRangeMode(start, end uint, mode ProofMode) (Proof, []uint, error) {
+	plan, err := n.rangePlan(start, end)
+	if err != nil {
+		return Proof{}, nil, err
+	}
+	return n.planAndCollectMode(plan, mode)
+}
+
+// ProveNamespaceMode performs ProveNamespace, creating a proof in mode. Like
+// ProveRangeMode, WithData proofs degrade to HashesOnly proofs if some of the
+// proven leaves have been pruned, whose positions are returned.
+func (n *NCMT) ProveNamespaceMode(nID namespace.ID, mode ProofMode) (Proof, []uint, error) {
+	plan, err := n.namespacePlan(nID)
+	if err != nil {
+		return Proof{}, nil, err
+	}
+	return n.planAndCollectMode(plan, mode)
+}
+
+// planAndCollectMode completes plan, collecting the proof in mode, or in
+// HashesOnly mode if any of its leaves have been pruned
+func (n *NCMT) planAndCollectMode(plan ProofPlan, mode ProofMode) (Proof, []uint, error) {
+	if mode != WithData && mode != HashesOnly {
+		return Proof{}, nil, fmt.Errorf("unknown proof mode %d", mode)
+	}
+	pruned := n.PrunedLeaves(plan.Start, plan.End)
+	if len(pruned) != 0 {
+		mode = HashesOnly
+	}
+	// without a deadline, planning cannot fail
+	_ = n.planSiblings(context.Background(), &plan)
+	return n.collect(plan, mode), pruned, nil
+}
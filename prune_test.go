@@ -0,0 +1,101 @@
+package ncmt
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrunePayloads(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	root := tree.Root()
+	full, err := tree.ProveRange(8, 16)
+	assert.NoError(t, err)
+
+	// prune the leaves of namespace 4
+	assert.NoError(t, tree.PrunePayloads(3, 6))
+	assert.Equal(t, []uint{3, 4, 5}, tree.PrunedLeaves(0, 32))
+
+	// requests that need the pruned data fail
+	_, err = tree.ProveRange(0, 8)
+	assert.True(t, errors.Is(err, ErrPayloadPruned))
+	_, err = tree.Get(4)
+	assert.True(t, errors.Is(err, ErrPayloadPruned))
+	_, err = tree.ProveLeafPooled(5)
+	assert.True(t, errors.Is(err, ErrPayloadPruned))
+
+	// WithData proofs degrade to HashesOnly proofs, reporting the pruned leaves
+	proof, pruned, err := tree.ProveRangeMode(0, 8, WithData)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{3, 4, 5}, pruned)
+	assert.True(t, proof.HashesOnly())
+	assert.Empty(t, proof.Data)
+	assert.True(t, Verify(root, nil, proof))
+
+	// while proofs of leaves that still have their payloads keep their data
+	proof, pruned, err = tree.ProveRangeMode(8, 16, WithData)
+	assert.NoError(t, err)
+	assert.Empty(t, pruned)
+	assert.Equal(t, full, proof)
+
+	// and can be requested without data
+	proof, _, err = tree.ProveRangeMode(8, 16, HashesOnly)
+	assert.NoError(t, err)
+	assert.True(t, proof.HashesOnly())
+	assert.True(t, Verify(root, nil, proof))
+
+	_, _, err = tree.ProveRangeMode(8, 16, ProofMode(2))
+	assert.Error(t, err)
+
+	// the tree must be built, and the range within the extended leaves
+	assert.Error(t, NewNCMT().PrunePayloads(0, 1))
+	assert.Error(t, tree.PrunePayloads(30, 33))
+}
+
+func TestHashesOnlyNamespaceProof(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	root := tree.Root()
+	assert.NoError(t, tree.PrunePayloads(3, 6))
+
+	proof, pruned, err := tree.ProveNamespaceMode(mockID(4), WithData)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{3, 4, 5}, pruned)
+	assert.True(t, proof.HashesOnly())
+	assert.False(t, proof.Absence())
+	assert.True(t, Verify(root, nil, proof))
+
+	// the encoding round trips
+	encoded, err := proof.MarshalCBOR()
+	assert.NoError(t, err)
+	var decoded Proof
+	assert.NoError(t, decoded.UnmarshalCBOR(encoded))
+	assert.Equal(t, proof, decoded)
+
+	// absence proofs can be hash only too
+	absence, _, err := tree.ProveNamespaceMode(mockID(5), HashesOnly)
+	assert.NoError(t, err)
+	assert.True(t, absence.Absence())
+	assert.True(t, Verify(root, nil, absence))
+
+	// leaving out a leaf of the namespace fails
+	partial, _, err := tree.ProveRangeMode(3, 5, HashesOnly)
+	assert.NoError(t, err)
+	partial.NamespaceID = proof.NamespaceID
+	assert.False(t, Verify(root, nil, partial))
+
+	// as does a tampered leaf hash
+	tampered := proof
+	tampered.LeafHashes = append([][]byte{}, proof.LeafHashes...)
+	tampered.LeafHashes[1] = append([]byte{}, proof.LeafHashes[1]...)
+	tampered.LeafHashes[1][len(tampered.LeafHashes[1])-1]++
+	assert.False(t, Verify(root, nil, tampered))
+
+	// and holding both data and hashes
+	withData, err := tree.ProveRange(8, 9)
+	assert.NoError(t, err)
+	hashed, _, err := tree.ProveRangeMode(8, 9, HashesOnly)
+	assert.NoError(t, err)
+	hashed.Data = withData.Data
+	assert.False(t, Verify(root, nil, hashed))
+}
@@ -0,0 +1,89 @@
+package ncmt
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// Coordinate locates a single symbol in the tree. Layer 0 is the extended leaf
+// layer, where indexes [0, OriginalWidth) are original leaves and the rest
+// are their erasures.
+type Coordinate struct {
+	Layer uint
+	Index uint
+}
+
+// PlanRecovery returns the minimal set of additional leaf symbols that must be
+// fetched in order to recover every leaf of the namespace nID. available marks
+// which of the extended leaves are already held, and must cover the entire
+// extended leaf layer. As each segment of the leaf layer is erasured as a
+// single codeword, any segment width worth of its symbols are enough to decode
+// the rest, so the namespace's own missing leaves are always fetched first.
+func (n *NCMT) PlanRecovery(nID namespace.ID, available []bool) ([]Coordinate, error) {
+	if len(n.layers) == 0 {
+		return nil, errors.New("tree must be built before planning a recovery")
+	}
+	if uint(len(available)) != 2*n.originalWidth {
+		return nil, fmt.Errorf(
+			"invalid availability: expected %d leaves, received %d",
+			2*n.originalWidth,
+			len(available),
+		)
+	}
+	found, start, end := n.foundInRange(nID)
+	if !found {
+		return nil, fmt.Errorf("namespace not found in tree: %s", FormatNamespace(nID))
+	}
+
+	var plan []Coordinate
+	for _, bounds := range segments(int(n.originalWidth), n.opts.SegmentSize) {
+		segStart, segEnd := uint(bounds[0]), uint(bounds[1])
+		availableCount := uint(0)
+		for i := segStart; i < segEnd; i++ {
+			if available[i] {
+				availableCount++
+			}
+			if available[n.originalWidth+i] {
+				availableCount++
+			}
+		}
+
+		var missing []Coordinate
+		for i := segStart; i < segEnd; i++ {
+			if i >= start && i < end && !available[i] {
+				missing = append(missing, Coordinate{Layer: 0, Index: i})
+			}
+		}
+
+		// fetching more than it takes to decode the segment is never required
+		segmentWidth := segEnd - segStart
+		if availableCount >= segmentWidth {
+			continue
+		}
+		if needed := segmentWidth - availableCount; needed < uint(len(missing)) {
+			missing = missing[:needed]
+		}
+		plan = append(plan, missing...)
+	}
+	return plan, nil
+}
+
+// CodingGroup returns the indexes of the original and erasured leaves that are
+// batched into the same first layer node as the leaf at leafIdx, which can be
+// either an original or an erasured leaf of the extended leaf layer. Note that
+// each layer, or segment of a layer, is erasured as a single codeword, so
+// decoding a leaf can require symbols from outside of its group.
+func (n *NCMT) CodingGroup(leafIdx uint) ([]uint, []uint, error) {
+	if len(n.layers) == 0 {
+		return nil, nil, errors.New("tree must be built before finding coding groups")
+	}
+	if leafIdx >= 2*n.originalWidth {
+		return nil, nil, fmt.Errorf("leaf %d is outside of the %d extended leaves", leafIdx, 2*n.originalWidth)
+	}
+	batchSize := uint(n.opts.BatchSize / 2)
+	parent := parentPositions([]uint{leafIdx}, n.originalWidth, batchSize)[0]
+	positions := batchPositions(parent, n.originalWidth, batchSize)
+	return positions[:batchSize], positions[batchSize:], nil
+}
@@ -0,0 +1,94 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/lazyledger/nmt/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanRecovery(t *testing.T) {
+	tree := mockTree(16, 4, t)
+	nID := mockID(5)
+
+	available := make([]bool, 32)
+	// nothing is available, so only the namespace's leaf needs fetching
+	plan, err := tree.PlanRecovery(nID, available)
+	assert.NoError(t, err)
+	assert.Equal(t, []Coordinate{{Layer: 0, Index: 5}}, plan)
+
+	// the namespace is available
+	available[5] = true
+	plan, err = tree.PlanRecovery(nID, available)
+	assert.NoError(t, err)
+	assert.Empty(t, plan)
+
+	// enough symbols are available to decode the entire layer
+	available[5] = false
+	for i := 16; i < 32; i++ {
+		available[i] = true
+	}
+	plan, err = tree.PlanRecovery(nID, available)
+	assert.NoError(t, err)
+	assert.Empty(t, plan)
+
+	// an unknown namespace and an incorrectly sized bitmap both fail
+	_, err = tree.PlanRecovery(mockID(200), available)
+	assert.Error(t, err)
+	_, err = tree.PlanRecovery(nID, available[:4])
+	assert.Error(t, err)
+}
+
+func TestPlanRecoveryPartial(t *testing.T) {
+	tree := NewNCMT()
+	// push a namespace that spans 6 of the 8 original leaves
+	for i, d := range mockData(8, 4) {
+		id := mockID(1)
+		if i < 2 {
+			id = mockID(0)
+		}
+		err := tree.Push(namespace.PrefixedDataFrom(id, d.Data()))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := tree.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	available := make([]bool, 16)
+	// with 5 symbols available, only 3 more are needed to decode
+	for _, i := range []int{0, 1, 12, 13, 14} {
+		available[i] = true
+	}
+	plan, err := tree.PlanRecovery(mockID(1), available)
+	assert.NoError(t, err)
+	assert.Equal(t, []Coordinate{{0, 2}, {0, 3}, {0, 4}}, plan)
+}
+
+func TestCodingGroup(t *testing.T) {
+	tree := mockTree(16, 4, t)
+	for _, idx := range []uint{4, 5, 20, 21} {
+		originals, parity, err := tree.CodingGroup(idx)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{4, 5}, originals)
+		assert.Equal(t, []uint{20, 21}, parity)
+	}
+
+	large := NewNCMT(func(o *Options) { o.BatchSize = 8 })
+	for _, d := range mockData(16, 4) {
+		assert.NoError(t, large.Push(d))
+	}
+	_, err := large.Build()
+	assert.NoError(t, err)
+	originals, parity, err := large.CodingGroup(31)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{12, 13, 14, 15}, originals)
+	assert.Equal(t, []uint{28, 29, 30, 31}, parity)
+
+	_, _, err = tree.CodingGroup(32)
+	assert.Error(t, err)
+	_, _, err = NewNCMT().CodingGroup(0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,189 @@
+package ncmt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// FetchFunc requests a proof of the single leaf at idx of the extended leaf
+// layer from a peer
+type FetchFunc func(ctx context.Context, idx uint) (Proof, error)
+
+// RemoteNodeGetter pulls leaves of the tree described by a TreeHeader from
+// peers through a FetchFunc. Every fetched proof is verified against the
+// header before it is returned or cached, and rejected fetches are retried.
+// At most a fixed number of fetches run at once across all callers. Verified
+// leaves are cached for the lifetime of the getter, so the cache is bounded by
+// the extended width of the tree.
+type RemoteNodeGetter struct {
+	header   TreeHeader
+	fetch    FetchFunc
+	attempts int
+	// slots holds a token for every fetch that is running
+	slots chan struct{}
+
+	mut   sync.Mutex
+	cache map[uint]Proof
+}
+
+// NewRemoteNodeGetter issues a new RemoteNodeGetter. The header must describe
+// the shape of the tree. Up to concurrency fetches run at once, defaulting to
+// GOMAXPROCS if concurrency is less than 1, and each leaf is fetched up to
+// attempts times, or once if attempts is less than 1.
+func NewRemoteNodeGetter(header TreeHeader, fetch FetchFunc, concurrency, attempts int) (*RemoteNodeGetter, error) {
+	err := header.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid header: %s", err)
+	}
+	if header.OriginalWidth == 0 {
+		return nil, errors.New("header does not describe the shape of the tree")
+	}
+	if fetch == nil {
+		return nil, errors.New("no fetch function")
+	}
+	if concurrency < 1 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+	return &RemoteNodeGetter{
+		header:   header,
+		fetch:    fetch,
+		attempts: attempts,
+		slots:    make(chan struct{}, concurrency),
+		cache:    make(map[uint]Proof),
+	}, nil
+}
+
+// GetLeaf returns a verified proof of the leaf at idx of the extended leaf
+// layer, holding its data
+func (g *RemoteNodeGetter) GetLeaf(ctx context.Context, idx uint) (Proof, error) {
+	if idx >= g.header.ExtendedWidth {
+		return Proof{}, fmt.Errorf("leaf %d is outside of the %d extended leaves", idx, g.header.ExtendedWidth)
+	}
+	cached, found := g.cached(idx)
+	if found {
+		return cached, nil
+	}
+
+	select {
+	case g.slots <- struct{}{}:
+	case <-ctx.Done():
+		return Proof{}, ctx.Err()
+	}
+	defer func() { <-g.slots }()
+	// the leaf may have been fetched while waiting for a slot
+	cached, found = g.cached(idx)
+	if found {
+		return cached, nil
+	}
+
+	var err error
+	for attempt := 0; attempt < g.attempts; attempt++ {
+		var proof Proof
+		proof, err = g.fetch(ctx, idx)
+		if err == nil {
+			err = g.check(idx, proof)
+		}
+		if err == nil {
+			g.mut.Lock()
+			g.cache[idx] = proof
+			g.mut.Unlock()
+			return proof, nil
+		}
+		if ctx.Err() != nil {
+			return Proof{}, ctx.Err()
+		}
+	}
+	return Proof{}, fmt.Errorf("failure to fetch leaf %d after %d attempts: %s", idx, g.attempts, err)
+}
+
+// cached returns the cached proof of the leaf at idx, if any
+func (g *RemoteNodeGetter) cached(idx uint) (Proof, bool) {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	proof, found := g.cache[idx]
+	return proof, found
+}
+
+// GetLeaves performs GetLeaf for every index concurrently, returning the
+// proofs in the same order. If any leaf cannot be fetched, the error of the
+// first such index is returned.
+func (g *RemoteNodeGetter) GetLeaves(ctx context.Context, indices []uint) ([]Proof, error) {
+	proofs := make([]Proof, len(indices))
+	errs := make([]error, len(indices))
+	var wg sync.WaitGroup
+	for i, idx := range indices {
+		wg.Add(1)
+		go func(i int, idx uint) {
+			defer wg.Done()
+			proofs[i], errs[i] = g.GetLeaf(ctx, idx)
+		}(i, idx)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return proofs, nil
+}
+
+// Repair fetches the leaves at indices and repairs the tree from them. The
+// setters configure the tree before the parameters of the header are applied,
+// so that a codec other than the default can be chosen.
+func (g *RemoteNodeGetter) Repair(ctx context.Context, indices []uint, setters ...Option) (*NCMT, error) {
+	samples, err := g.GetLeaves(ctx, indices)
+	if err != nil {
+		return nil, err
+	}
+	setters = append(append([]Option{}, setters...), g.header.ApplyTo())
+	return Repair(g.header.Root, NewNCMT(setters...).opts, samples)
+}
+
+// check ensures that p is a verified proof of the data of the leaf at idx
+func (g *RemoteNodeGetter) check(idx uint, p Proof) error {
+	if p.Start != idx || p.End != idx+1 {
+		return fmt.Errorf("proof of [%d, %d) does not prove leaf %d", p.Start, p.End, idx)
+	}
+	if p.HashesOnly() {
+		return errors.New("proof does not hold the data of the leaf")
+	}
+	if !VerifyWithHeader(g.header, p) {
+		return errors.New("proof does not match the header")
+	}
+	return nil
+}
+
+// RestorePayloads fetches the pruned leaves in [start, end) of the extended
+// leaf layer through g, whose header must have the root of the tree, and
+// restores their payloads
+func (n *NCMT) RestorePayloads(ctx context.Context, g *RemoteNodeGetter, start, end uint) error {
+	if len(n.layers) == 0 {
+		return errors.New("tree must be built before restoring payloads")
+	}
+	if !bytes.Equal(g.header.Root, n.Root()) {
+		return errors.New("getter belongs to a different tree")
+	}
+	pruned := n.PrunedLeaves(start, end)
+	proofs, err := g.GetLeaves(ctx, pruned)
+	if err != nil {
+		return err
+	}
+	for i, idx := range pruned {
+		data, err := ParseNamespacedData(n.opts.NamespaceSize, proofs[i].Data[0])
+		if err != nil {
+			return fmt.Errorf("invalid data of leaf %d: %s", idx, err)
+		}
+		// the proof is verified against the root, so the data hashes to the
+		// retained leaf hash
+		n.leaves[idx].data = NewNamespacedData(data.ID, data.Payload)
+		n.leaves[idx].pruned = false
+	}
+	return nil
+}
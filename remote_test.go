@@ -0,0 +1,161 @@
+package ncmt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// peer serves proofs of a tree, failing the first request for every leaf, and
+// tracking how many requests run at once
+type peer struct {
+	tree *NCMT
+
+	mut      sync.Mutex
+	requests map[uint]int
+
+	running, maxRunning int32
+}
+
+func newPeer(tree *NCMT) *peer {
+	return &peer{tree: tree, requests: make(map[uint]int)}
+}
+
+func (p *peer) fetch(ctx context.Context, idx uint) (Proof, error) {
+	running := atomic.AddInt32(&p.running, 1)
+	defer atomic.AddInt32(&p.running, -1)
+	for {
+		max := atomic.LoadInt32(&p.maxRunning)
+		if running <= max || atomic.CompareAndSwapInt32(&p.maxRunning, max, running) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+
+	p.mut.Lock()
+	p.requests[idx]++
+	first := p.requests[idx] == 1
+	p.mut.Unlock()
+	if first {
+		return Proof{}, errors.New("peer is busy")
+	}
+	return p.tree.ProveLeaf(idx)
+}
+
+func TestRemoteNodeGetter(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	header, err := tree.Header()
+	assert.NoError(t, err)
+	remote := newPeer(tree)
+
+	getter, err := NewRemoteNodeGetter(header, remote.fetch, 2, 2)
+	assert.NoError(t, err)
+	indices := rangePositions(8, 24)
+	proofs, err := getter.GetLeaves(context.Background(), indices)
+	assert.NoError(t, err)
+	for i, proof := range proofs {
+		assert.Equal(t, indices[i], proof.Start)
+		assert.True(t, Verify(tree.Root(), nil, proof))
+	}
+	assert.LessOrEqual(t, remote.maxRunning, int32(2))
+
+	// verified leaves are cached
+	_, err = getter.GetLeaf(context.Background(), 8)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, remote.requests[8])
+
+	// a single attempt does not survive the failed first request
+	getter, err = NewRemoteNodeGetter(header, newPeer(tree).fetch, 1, 1)
+	assert.NoError(t, err)
+	_, err = getter.GetLeaf(context.Background(), 3)
+	assert.Error(t, err)
+
+	// leaves outside of the tree are not fetched
+	_, err = getter.GetLeaf(context.Background(), 32)
+	assert.Error(t, err)
+}
+
+func TestRemoteNodeGetterVerifies(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	header, err := tree.Header()
+	assert.NoError(t, err)
+
+	for name, fetch := range map[string]FetchFunc{
+		"tampered data": func(ctx context.Context, idx uint) (Proof, error) {
+			proof, err := tree.ProveLeaf(idx)
+			proof.Data[0] = append([]byte{}, proof.Data[0]...)
+			proof.Data[0][len(proof.Data[0])-1]++
+			return proof, err
+		},
+		"another leaf": func(ctx context.Context, idx uint) (Proof, error) {
+			return tree.ProveLeaf(idx + 1)
+		},
+		"hashes only": func(ctx context.Context, idx uint) (Proof, error) {
+			proof, _, err := tree.ProveRangeMode(idx, idx+1, HashesOnly)
+			return proof, err
+		},
+	} {
+		getter, err := NewRemoteNodeGetter(header, fetch, 1, 3)
+		assert.NoError(t, err)
+		_, err = getter.GetLeaf(context.Background(), 2)
+		assert.Error(t, err, name)
+	}
+
+	// a header without the shape of the tree cannot bound the leaves
+	_, err = NewRemoteNodeGetter(TreeHeader{Root: tree.Root(), BatchSize: 4}, newPeer(tree).fetch, 1, 1)
+	assert.Error(t, err)
+}
+
+func TestRemoteNodeGetterRepair(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	header, err := tree.Header()
+	assert.NoError(t, err)
+	getter, err := NewRemoteNodeGetter(header, newPeer(tree).fetch, 4, 2)
+	assert.NoError(t, err)
+
+	// an original width worth of parity leaves is enough to repair the tree
+	repaired, err := getter.Repair(context.Background(), rangePositions(16, 32))
+	assert.NoError(t, err)
+	assert.Equal(t, tree.Root(), repaired.Root())
+}
+
+func TestRestorePayloads(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	header, err := tree.Header()
+	assert.NoError(t, err)
+	expected, err := tree.ProveRange(0, 8)
+	assert.NoError(t, err)
+
+	// a copy of the tree whose payloads have been pruned
+	pruned := NewNCMT()
+	for i := uint(0); i < tree.OriginalWidth(); i++ {
+		data, err := tree.Get(i)
+		assert.NoError(t, err)
+		assert.NoError(t, pruned.Push(data))
+	}
+	_, err = pruned.Build()
+	assert.NoError(t, err)
+	assert.NoError(t, pruned.PrunePayloads(3, 6))
+	getter, err := NewRemoteNodeGetter(header, newPeer(tree).fetch, 2, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, pruned.RestorePayloads(context.Background(), getter, 0, 16))
+	assert.Empty(t, pruned.PrunedLeaves(0, 32))
+
+	proof, err := pruned.ProveRange(0, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, proof)
+
+	// the getter must belong to the tree
+	other := NewNCMT()
+	for _, d := range mockData(16, 8) {
+		assert.NoError(t, other.Push(d))
+	}
+	_, err = other.Build()
+	assert.NoError(t, err)
+	assert.Error(t, other.RestorePayloads(context.Background(), getter, 0, 16))
+}
@@ -0,0 +1,368 @@
+package ncmt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lazyledger/nmt/namespace"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+/////////////////////////////////////////
+//  Sampling
+///////////////////////////////////////
+
+// Sample creates a proof for each of the symbols at indices of the extended
+// leaf layer. Every layer above the leaves is derived from them, so any
+// original width worth of verified samples is enough to Repair the tree.
+func (n *NCMT) Sample(indices []uint) ([]Proof, error) {
+	samples := make([]Proof, len(indices))
+	for i, idx := range indices {
+		proof, err := n.ProveLeaf(idx)
+		if err != nil {
+			return nil, fmt.Errorf("failure to sample symbol %d: %s", idx, err)
+		}
+		samples[i] = proof
+	}
+	return samples, nil
+}
+
+/////////////////////////////////////////
+//  Reconstruction and fraud proofs
+///////////////////////////////////////
+
+// BadEncodingProof shows that a tree was not erasured correctly. It either
+// holds enough valid samples to decode the leaf layer, which, when rebuilt,
+// does not produce the committed root, or a single Segment of a layer whose
+// committed parity is not the erasure of its committed originals. A correctly
+// erasured tree produces the same root from any sufficient subset of its
+// symbols, and the parity of every segment matches its originals.
+type BadEncodingProof struct {
+	Samples []Proof       `cbor:"samples"`
+	Segment *SegmentProof `cbor:"segment,omitempty"`
+}
+
+// BadEncodingError is returned by Repair when the symbols decode to a tree
+// other than the one committed to
+type BadEncodingError struct {
+	Proof BadEncodingProof
+}
+
+func (e *BadEncodingError) Error() string {
+	return "bad encoding: repaired tree does not match the committed root"
+}
+
+// Repair verifies samples against root and decodes them into the full tree.
+// The samples must include an original width worth of symbols, and the
+// namespace of every leaf, which is revealed by either symbol at its index or
+// by a sibling leaf hash. If the decoded tree does not match root, a
+// *BadEncodingError holding a BadEncodingProof is returned. If opts is nil,
+// the default options are used.
+func Repair(root []byte, opts *Options, samples []Proof) (*NCMT, error) {
+	if opts == nil {
+		opts = NewNCMT().opts
+	}
+	tree, err := repair(root, opts, samples)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(tree.Root(), root) {
+		return nil, &BadEncodingError{Proof: BadEncodingProof{Samples: samples}}
+	}
+	return tree, nil
+}
+
+// VerifyBadEncoding checks that p proves the tree committed to by root was
+// erasured incorrectly. If opts is nil, the default options are used.
+func VerifyBadEncoding(root []byte, opts *Options, p BadEncodingProof) bool {
+	if opts == nil {
+		opts = NewNCMT().opts
+	}
+	if p.Segment != nil {
+		bad, err := checkSegment(root, opts, *p.Segment)
+		return err == nil && bad
+	}
+	tree, err := repair(root, opts, p.Samples)
+	if err != nil {
+		return false
+	}
+	return !bytes.Equal(tree.Root(), root)
+}
+
+// repair decodes the verified samples and rebuilds the tree from them without
+// comparing the result to root
+func repair(root []byte, opts *Options, samples []Proof) (*NCMT, error) {
+	if len(samples) == 0 {
+		return nil, errors.New("no samples to repair from")
+	}
+	// the width is checked against the samples before anything is allocated
+	// for it, as it is chosen by whoever created them
+	width := samples[0].Width
+	if width == 0 || width > uint(opts.maxLeaves()) {
+		return nil, fmt.Errorf("invalid width %d", width)
+	}
+	provided := uint(0)
+	for i, sample := range samples {
+		if sample.Width != width {
+			return nil, fmt.Errorf("invalid sample %d: width %d does not match %d", i, sample.Width, width)
+		}
+		provided += uint(len(sample.Data))
+	}
+	if provided < width {
+		return nil, fmt.Errorf("not enough symbols to repair: need %d, have %d", width, provided)
+	}
+	for i, sample := range samples {
+		err := verify(root, opts, sample)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample %d: %s", i, err)
+		}
+	}
+
+	nsSize := int(opts.NamespaceSize)
+	batchSize := uint(opts.BatchSize / 2)
+	symbols := make([][]byte, 2*width)
+	ids := make([]namespace.ID, width)
+	for _, sample := range samples {
+		for j, raw := range sample.Data {
+			pos := sample.Start + uint(j)
+			symbols[pos] = raw[nsSize:]
+			if ids[pos%width] == nil {
+				ids[pos%width] = namespace.ID(raw[:nsSize])
+			}
+		}
+		// the leaf hashes of siblings are prefixed with their namespace. The
+		// length of parity siblings is not checked by verify, as it does not
+		// interpret them.
+		for pos, hash := range leafSiblings(sample, batchSize) {
+			if len(hash) < nsSize {
+				return nil, fmt.Errorf("sibling leaf hash %d is too short", pos)
+			}
+			if ids[pos%width] == nil {
+				ids[pos%width] = namespace.ID(hash[:nsSize])
+			}
+		}
+	}
+
+	available := uint(0)
+	for _, symbol := range symbols {
+		if symbol != nil {
+			available++
+		}
+	}
+	if available < width {
+		return nil, fmt.Errorf("not enough symbols to repair: need %d, have %d", width, available)
+	}
+	for i, id := range ids {
+		if id == nil {
+			return nil, fmt.Errorf("the namespace of leaf %d is unknown", i)
+		}
+	}
+
+	_, span := startSpan(context.Background(), opts.Tracer, "ncmt.Decode",
+		attribute.Int("ncmt.layer", 0),
+		attribute.String("ncmt.codec", opts.Codec.ID()),
+	)
+	decoded, err := decodeSegments(opts.Codec, symbols, opts.SegmentSize)
+	endSpan(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("failure to decode samples: %s", err)
+	}
+
+	tree := NewNCMT(preset(*opts))
+	for i, payload := range decoded[:width] {
+		err := tree.Push(NewNamespacedData(ids[i], payload))
+		if err != nil {
+			return nil, fmt.Errorf("failure to push repaired leaf %d: %s", i, err)
+		}
+	}
+	_, err = tree.Build()
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// leafSiblings maps the positions of the leaf layer siblings in an already
+// verified proof to their hashes
+func leafSiblings(p Proof, batchSize uint) map[uint][]byte {
+	siblings := make(map[uint][]byte)
+	if p.Width <= 1 {
+		return siblings
+	}
+	known := rangePositions(p.Start, p.End)
+	set := p.Set
+	for _, parent := range parentPositions(known, p.Width, batchSize) {
+		for _, child := range batchPositions(parent, p.Width, batchSize) {
+			if containsPosition(known, child) {
+				continue
+			}
+			siblings[child] = set[0]
+			set = set[1:]
+		}
+	}
+	return siblings
+}
+
+/////////////////////////////////////////
+//  Single layer fraud proofs
+///////////////////////////////////////
+
+// SegmentProof reveals every symbol of one segment of a layer, which is the
+// whole layer unless WithSegmentSize is used, so that the parity of the
+// segment can be checked against its originals without decoding the tree.
+type SegmentProof struct {
+	// Parity proves every parity symbol of the segment. The originals of the
+	// segment are in the same batches, so their hashes are among its siblings.
+	Parity ParityProof `cbor:"parity"`
+	// Leaves proves the original leaves of the segment. It is only set for the
+	// leaf layer, whose symbols are leaf data rather than hashes.
+	Leaves *Proof `cbor:"leaves,omitempty"`
+}
+
+// ProveSegment creates a proof of every symbol of a segment of layer, where
+// layer 0 is the leaf layer. Every layer below the root can be proven.
+func (n *NCMT) ProveSegment(layer, segment int) (SegmentProof, error) {
+	if len(n.layers) == 0 {
+		return SegmentProof{}, errors.New("tree must be built before creating proofs")
+	}
+	if layer < 0 || layer >= n.Depth() {
+		return SegmentProof{}, fmt.Errorf("layer %d has no parity, the tree has %d layers below its root", layer, n.Depth())
+	}
+	bounds := segments(int(n.levelWidth(layer)), n.opts.SegmentSize)
+	if segment < 0 || segment >= len(bounds) {
+		return SegmentProof{}, fmt.Errorf("segment %d is outside of the %d segments of layer %d", segment, len(bounds), layer)
+	}
+	start, end := uint(bounds[segment][0]), uint(bounds[segment][1])
+	parity, err := n.ProveParityRange(layer, start, end)
+	if err != nil {
+		return SegmentProof{}, err
+	}
+	proof := SegmentProof{Parity: parity}
+	if layer == 0 {
+		leaves, err := n.prove(start, end)
+		if err != nil {
+			return SegmentProof{}, err
+		}
+		proof.Leaves = &leaves
+	}
+	return proof, nil
+}
+
+// CheckSegment verifies p against root, then checks that the parity of the
+// segment is the erasure of its originals. If it is not, a *BadEncodingError
+// holding p is returned. If opts is nil, the default options are used.
+func CheckSegment(root []byte, opts *Options, p SegmentProof) error {
+	if opts == nil {
+		opts = NewNCMT().opts
+	}
+	bad, err := checkSegment(root, opts, p)
+	if err != nil {
+		return err
+	}
+	if bad {
+		return &BadEncodingError{Proof: BadEncodingProof{Segment: &p}}
+	}
+	return nil
+}
+
+// checkSegment returns true if p is valid for root and the committed parity of
+// the segment is not the erasure of its committed originals
+func checkSegment(root []byte, opts *Options, p SegmentProof) (bool, error) {
+	err := verifyParity(root, opts, p.Parity)
+	if err != nil {
+		return false, fmt.Errorf("invalid parity: %s", err)
+	}
+	batchSize := uint(opts.BatchSize / 2)
+	width, err := parityLayerWidth(p.Parity.Width, p.Parity.Layer, batchSize)
+	if err != nil {
+		return false, err
+	}
+	start, end := p.Parity.Start, p.Parity.End
+	// the segments of the layer are not listed, as the width is chosen by
+	// whoever created the proof
+	size := uint(opts.SegmentSize)
+	if opts.SegmentSize <= 0 || size >= width {
+		size = width
+	}
+	if start%size != 0 || end != start+size {
+		return false, fmt.Errorf("range [%d, %d) is not a segment of layer %d", start, end, p.Parity.Layer)
+	}
+
+	originals, err := segmentOriginals(root, opts, p, width)
+	if err != nil {
+		return false, err
+	}
+	parity := p.Parity.Symbols
+	nsSize := int(opts.NamespaceSize)
+	if p.Parity.Layer == 0 {
+		// parity leaves take the namespace of the original at the same index
+		parity = make([][]byte, len(p.Parity.Symbols))
+		for i, symbol := range p.Parity.Symbols {
+			if !bytes.Equal(symbol[:nsSize], p.Leaves.Data[i][:nsSize]) {
+				return true, nil
+			}
+			parity[i] = symbol[nsSize:]
+		}
+	}
+	encoded, err := opts.Codec.Encode(originals)
+	if err != nil {
+		return false, fmt.Errorf("failure to encode the originals of the segment: %s", err)
+	}
+	if len(encoded) != len(parity) {
+		return false, fmt.Errorf("codec %s returned %d parity symbols for %d originals", opts.Codec.ID(), len(encoded), len(originals))
+	}
+	for i := range encoded {
+		if !bytes.Equal(encoded[i], parity[i]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// segmentOriginals returns the original symbols of the segment proven by p,
+// which has already been verified, of a layer with width originals
+func segmentOriginals(root []byte, opts *Options, p SegmentProof, width uint) ([][]byte, error) {
+	start, end := p.Parity.Start, p.Parity.End
+	if p.Parity.Layer == 0 {
+		if p.Leaves == nil {
+			return nil, errors.New("missing the original leaves of the segment")
+		}
+		leaves := *p.Leaves
+		if leaves.Start != start || leaves.End != end || leaves.Width != p.Parity.Width {
+			return nil, fmt.Errorf("leaves [%d, %d) do not match the segment [%d, %d)", leaves.Start, leaves.End, start, end)
+		}
+		err := verify(root, opts, leaves)
+		if err != nil {
+			return nil, fmt.Errorf("invalid leaves: %s", err)
+		}
+		originals := make([][]byte, len(leaves.Data))
+		for i, data := range leaves.Data {
+			originals[i] = data[opts.NamespaceSize:]
+		}
+		return originals, nil
+	}
+	if p.Leaves != nil {
+		return nil, fmt.Errorf("unexpected leaves for layer %d", p.Parity.Layer)
+	}
+
+	// the siblings of the parity are in proof order, and those at the layer of
+	// the segment include every original of the segment
+	batchSize := uint(opts.BatchSize / 2)
+	known := rangePositions(width+start, width+end)
+	originals := make([][]byte, end-start)
+	set := p.Parity.Set
+	for _, parent := range parentPositions(known, width, batchSize) {
+		for _, child := range batchPositions(parent, width, batchSize) {
+			if containsPosition(known, child) {
+				continue
+			}
+			if child >= start && child < end {
+				originals[child-start] = set[0]
+			}
+			set = set[1:]
+		}
+	}
+	return originals, nil
+}
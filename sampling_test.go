@@ -0,0 +1,182 @@
+package ncmt
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// badCodec corrupts the first parity symbol of every encoding
+type badCodec struct {
+	RSFG8
+}
+
+func (c badCodec) Encode(input [][]byte) ([][]byte, error) {
+	encoded, err := c.RSFG8.Encode(input)
+	if err != nil {
+		return nil, err
+	}
+	encoded[0] = append([]byte{}, encoded[0]...)
+	encoded[0][0]++
+	return encoded, nil
+}
+
+func TestRepair(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	root := tree.Root()
+
+	for _, indices := range [][]uint{
+		rangePositions(0, 16),
+		rangePositions(16, 32),
+		append(rangePositions(0, 8), rangePositions(24, 32)...),
+		{0, 2, 4, 6, 8, 10, 12, 14, 17, 19, 21, 23, 25, 27, 29, 31},
+	} {
+		samples, err := tree.Sample(indices)
+		assert.NoError(t, err)
+		repaired, err := Repair(root, nil, samples)
+		assert.NoError(t, err)
+		assert.Equal(t, root, repaired.Root())
+		for i := uint(0); i < 16; i++ {
+			expected, err := tree.Get(i)
+			assert.NoError(t, err)
+			got, err := repaired.Get(i)
+			assert.NoError(t, err)
+			assert.Equal(t, expected.NamespaceID(), got.NamespaceID())
+			assert.Equal(t, expected.Data(), got.Data())
+		}
+	}
+
+	// too few symbols
+	samples, err := tree.Sample(rangePositions(0, 15))
+	assert.NoError(t, err)
+	_, err = Repair(root, nil, samples)
+	assert.Error(t, err)
+
+	// enough symbols, but nothing reveals the namespaces of the last batch
+	samples, err = tree.Sample(append(rangePositions(0, 14), rangePositions(16, 30)...))
+	assert.NoError(t, err)
+	_, err = Repair(root, nil, samples)
+	assert.Error(t, err)
+
+	// samples from another tree
+	samples, err = sharedNamespaceTree(t).Sample(rangePositions(0, 16))
+	assert.NoError(t, err)
+	_, err = Repair(root, nil, samples)
+	assert.Error(t, err)
+
+	_, err = tree.Sample([]uint{32})
+	assert.Error(t, err)
+
+	// the width of the samples is checked before it is used
+	forged := BadEncodingProof{Samples: []Proof{{End: 1, Width: 1 << 62}}}
+	assert.False(t, VerifyBadEncoding(root, nil, forged))
+	_, err = Repair(root, nil, forged.Samples)
+	assert.Error(t, err)
+	samples, err = tree.Sample(rangePositions(0, 16))
+	assert.NoError(t, err)
+	samples[3].Width = 32
+	_, err = Repair(root, nil, samples)
+	assert.Error(t, err)
+}
+
+func TestBadEncoding(t *testing.T) {
+	honest := sharedNamespaceTree(t)
+	samples, err := honest.Sample(rangePositions(16, 32))
+	assert.NoError(t, err)
+	assert.False(t, VerifyBadEncoding(honest.Root(), nil, BadEncodingProof{Samples: samples}))
+
+	bad := sharedNamespaceTree(t, WithCodec(badCodec{}))
+	root := bad.Root()
+
+	// any sufficient set of symbols exposes the corrupted parity
+	samples, err = bad.Sample(rangePositions(0, 16))
+	assert.NoError(t, err)
+	_, err = Repair(root, nil, samples)
+	assert.Error(t, err)
+
+	samples, err = bad.Sample(rangePositions(16, 32))
+	assert.NoError(t, err)
+	_, err = Repair(root, nil, samples)
+	var badEncoding *BadEncodingError
+	assert.True(t, errors.As(err, &badEncoding))
+	assert.True(t, VerifyBadEncoding(root, nil, badEncoding.Proof))
+	assert.False(t, VerifyBadEncoding(honest.Root(), nil, badEncoding.Proof))
+}
+
+func TestSegmentProof(t *testing.T) {
+	for _, setters := range [][]Option{nil, {WithSegmentSize(4)}, {WithLeafCounts()}} {
+		honest := sharedNamespaceTree(t, setters...)
+		opts := honest.Options()
+		bad := sharedNamespaceTree(t, append([]Option{WithCodec(badCodec{})}, setters...)...)
+		for layer := 0; layer < honest.Depth(); layer++ {
+			segmentCount := len(segments(int(honest.levelWidth(layer)), opts.SegmentSize))
+			for segment := 0; segment < segmentCount; segment++ {
+				proof, err := honest.ProveSegment(layer, segment)
+				assert.NoError(t, err)
+				assert.NoError(t, CheckSegment(honest.Root(), &opts, proof))
+				assert.False(t, VerifyBadEncoding(honest.Root(), &opts, BadEncodingProof{Segment: &proof}))
+
+				// the first parity symbol of every codeword is corrupted
+				proof, err = bad.ProveSegment(layer, segment)
+				assert.NoError(t, err)
+				err = CheckSegment(bad.Root(), &opts, proof)
+				var badEncoding *BadEncodingError
+				assert.True(t, errors.As(err, &badEncoding), "layer %d segment %d", layer, segment)
+				assert.True(t, VerifyBadEncoding(bad.Root(), &opts, badEncoding.Proof))
+				assert.False(t, VerifyBadEncoding(honest.Root(), &opts, badEncoding.Proof))
+			}
+		}
+	}
+
+	tree := sharedNamespaceTree(t)
+	_, err := tree.ProveSegment(tree.Depth(), 0)
+	assert.Error(t, err)
+	_, err = tree.ProveSegment(0, 1)
+	assert.Error(t, err)
+
+	// a range that is not a whole segment is rejected
+	parity, err := tree.ProveParityRange(1, 0, 2)
+	assert.NoError(t, err)
+	assert.Error(t, CheckSegment(tree.Root(), nil, SegmentProof{Parity: parity}))
+	// as are leaves that are missing or do not match the segment
+	proof, err := tree.ProveSegment(0, 0)
+	assert.NoError(t, err)
+	leaves := proof.Leaves
+	proof.Leaves = nil
+	assert.Error(t, CheckSegment(tree.Root(), nil, proof))
+	short, err := tree.prove(0, 8)
+	assert.NoError(t, err)
+	proof.Leaves = &short
+	assert.Error(t, CheckSegment(tree.Root(), nil, proof))
+	proof.Leaves = leaves
+	assert.NoError(t, CheckSegment(tree.Root(), nil, proof))
+}
+
+func TestRepairShortSibling(t *testing.T) {
+	// rebuild a tree whose parity leaves 16 and 17 have empty hashes, which
+	// verify does not interpret
+	tree := sharedNamespaceTree(t)
+	width := tree.OriginalWidth()
+	tree.leaves[width].hash = []byte{}
+	tree.leaves[width+1].hash = []byte{}
+	tree.layers = []layer{tree.batchLeaves(tree.leaves[:width], tree.leaves[width:])}
+	tree.extendedLayers = nil
+	for len(tree.layers[len(tree.layers)-1]) > 1 {
+		next, err := tree.consolidateNodes(context.Background())
+		assert.NoError(t, err)
+		tree.layers = append(tree.layers, next)
+	}
+	root := tree.Root()
+
+	// the originals verify, with the empty hashes among their siblings
+	samples, err := tree.Sample(rangePositions(0, 16))
+	assert.NoError(t, err)
+	for _, sample := range samples {
+		assert.True(t, Verify(root, nil, sample))
+	}
+	_, err = Repair(root, nil, samples)
+	assert.Error(t, err)
+	assert.False(t, VerifyBadEncoding(root, nil, BadEncodingProof{Samples: samples}))
+}
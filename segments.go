@@ -0,0 +1,115 @@
+package ncmt
+
+import (
+	"fmt"
+	"math"
+)
+
+// WithSegmentSize erasures every layer that is wider than size original
+// nodes as independent segments of size consecutive originals. The parity of
+// a segment takes the same positions in the parity half of the layer as its
+// originals, so batching, proofs and verification are unchanged, and a codec
+// only needs to support size leaves to serve trees of any width. Decoding a
+// layer requires size symbols from every segment instead of an original width
+// worth from anywhere in the layer. Layers no wider than size, and every layer
+// if size is 0, are erasured as a single codeword.
+func WithSegmentSize(size int) Option {
+	return func(o *Options) {
+		o.SegmentSize = size
+	}
+}
+
+// maxLeaves is the number of original leaves the codec can erasure, which is
+// unlimited if layers are segmented
+func (o *Options) maxLeaves() int {
+	if o.SegmentSize > 0 {
+		return math.MaxInt
+	}
+	return o.Codec.MaxLeaves()
+}
+
+// checkSegments ensures that every layer of a tree with width original leaves
+// can be divided into segments
+func (o *Options) checkSegments(width int) error {
+	if o.SegmentSize <= 0 {
+		return nil
+	}
+	if o.SegmentSize > o.Codec.MaxLeaves() {
+		return fmt.Errorf(
+			"segment size %d exceeds the %d leaves supported by codec %s",
+			o.SegmentSize,
+			o.Codec.MaxLeaves(),
+			o.Codec.ID(),
+		)
+	}
+	for ; width > 1; width = width / (o.BatchSize / 2) {
+		if width > o.SegmentSize && width%o.SegmentSize != 0 {
+			return fmt.Errorf("layer of width %d cannot be divided into segments of %d", width, o.SegmentSize)
+		}
+	}
+	return nil
+}
+
+// segments returns the bounds [start, end) of each segment of a layer with
+// width originals
+func segments(width, size int) [][2]int {
+	if size <= 0 || size >= width {
+		return [][2]int{{0, width}}
+	}
+	bounds := make([][2]int, 0, width/size)
+	for start := 0; start < width; start += size {
+		bounds = append(bounds, [2]int{start, start + size})
+	}
+	return bounds
+}
+
+// encodeSegments erasures each segment of originals, returning the parity of
+// every original in order. If the codec is concurrent, segments are erasured
+// by up to workers goroutines.
+func encodeSegments(c Codec, originals [][]byte, size, workers int) ([][]byte, error) {
+	bounds := segments(len(originals), size)
+	encoded := make([][][]byte, len(bounds))
+	errs := make([]error, len(bounds))
+	forEachBatch(len(bounds), codecWorkers(c, workers), func(i int) {
+		encoded[i], errs[i] = c.Encode(originals[bounds[i][0]:bounds[i][1]])
+	})
+	parity := make([][]byte, 0, len(originals))
+	for i := range bounds {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		parity = append(parity, encoded[i]...)
+	}
+	return parity, nil
+}
+
+// decodeSegments decodes each segment of an extended layer, where missing
+// symbols are nil, returning the originals in order
+func decodeSegments(c Codec, symbols [][]byte, size int) ([][]byte, error) {
+	width := len(symbols) / 2
+	originals := make([][]byte, 0, width)
+	for i, bounds := range segments(width, size) {
+		segmentWidth := bounds[1] - bounds[0]
+		segment := make([][]byte, 0, 2*segmentWidth)
+		segment = append(segment, symbols[bounds[0]:bounds[1]]...)
+		segment = append(segment, symbols[width+bounds[0]:width+bounds[1]]...)
+		available := 0
+		for _, symbol := range segment {
+			if symbol != nil {
+				available++
+			}
+		}
+		if available < segmentWidth {
+			return nil, fmt.Errorf("segment %d has %d of the %d symbols needed to decode it", i, available, segmentWidth)
+		}
+		decoded, err := c.Decode(segment)
+		if err != nil {
+			return nil, err
+		}
+		if len(decoded) < segmentWidth {
+			return nil, fmt.Errorf("codec %s decoded %d symbols, expected %d", c.ID(), len(decoded), segmentWidth)
+		}
+		originals = append(originals, decoded[:segmentWidth]...)
+	}
+	return originals, nil
+}
@@ -0,0 +1,142 @@
+package ncmt
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmentedEncoding(t *testing.T) {
+	// 256 leaves exceed the 128 supported by RSFG8
+	data := mockData(256, 8)
+	unsegmented := NewNCMT()
+	for _, d := range data[:128] {
+		assert.NoError(t, unsegmented.Push(d))
+	}
+	assert.Error(t, unsegmented.Push(data[128]))
+
+	tree := NewNCMT(WithSegmentSize(128))
+	for _, d := range data {
+		assert.NoError(t, tree.Push(d))
+	}
+	assert.NoError(t, tree.Validate())
+	root, err := tree.Build()
+	assert.NoError(t, err)
+	assert.NoError(t, CheckInvariants(tree))
+
+	// each segment of the parity is the extension of its own originals
+	for _, bounds := range segments(256, 128) {
+		var originals, parity []NamespacedData
+		for i := bounds[0]; i < bounds[1]; i++ {
+			originals = append(originals, NamespacedData{ID: tree.leaves[i].data.NamespaceID(), Payload: tree.leaves[i].data.Data()})
+			parity = append(parity, NamespacedData{ID: tree.leaves[256+i].data.NamespaceID(), Payload: tree.leaves[256+i].data.Data()})
+		}
+		assert.NoError(t, VerifyExtension(RSFG8{}, originals, parity))
+	}
+
+	// proofs are unaffected by segments
+	header, err := tree.Header()
+	assert.NoError(t, err)
+	assert.Equal(t, 128, header.SegmentSize)
+	assert.NoError(t, header.Validate())
+	proof, err := tree.ProveNamespace(mockID(200))
+	assert.NoError(t, err)
+	assert.True(t, VerifyWithHeader(header, proof))
+	parity, err := tree.ProveRange(300, 310)
+	assert.NoError(t, err)
+	assert.True(t, VerifyWithHeader(header, parity))
+
+	// every segment must be decodable on its own
+	opts := tree.Options()
+	samples, err := tree.Sample(rangePositions(256, 512))
+	assert.NoError(t, err)
+	repaired, err := Repair(root, &opts, samples)
+	assert.NoError(t, err)
+	assert.Equal(t, root, repaired.Root())
+	samples, err = tree.Sample(append(rangePositions(0, 128), rangePositions(256, 384)...))
+	assert.NoError(t, err)
+	_, err = Repair(root, &opts, samples)
+	assert.Error(t, err)
+
+	// recovery only fetches from segments that cannot be decoded
+	available := make([]bool, 512)
+	for i := 0; i < 128; i++ {
+		available[i] = true
+	}
+	plan, err := tree.PlanRecovery(mockID(5), available)
+	assert.NoError(t, err)
+	assert.Empty(t, plan)
+	plan, err = tree.PlanRecovery(mockID(200), available)
+	assert.NoError(t, err)
+	assert.Equal(t, []Coordinate{{Layer: 0, Index: 200}}, plan)
+}
+
+func TestSegmentedLayers(t *testing.T) {
+	data := mockData(64, 8)
+	build := func(setters ...Option) *NCMT {
+		tree := NewNCMT(setters...)
+		for _, d := range data {
+			assert.NoError(t, tree.Push(d))
+		}
+		_, err := tree.Build()
+		assert.NoError(t, err)
+		return tree
+	}
+	whole := build()
+	// layers of width 64 and 32 are segmented, narrower layers are not
+	segmented := build(WithSegmentSize(16))
+	assert.NoError(t, CheckInvariants(segmented))
+	assert.NotEqual(t, whole.Root(), segmented.Root())
+	assert.Equal(t, whole.Root(), build(WithSegmentSize(64)).Root())
+
+	opts := segmented.Options()
+	proof, err := segmented.ProveRange(10, 20)
+	assert.NoError(t, err)
+	assert.True(t, Verify(segmented.Root(), &opts, proof))
+
+	tree := NewNCMT(WithSegmentSize(24))
+	for _, d := range data {
+		assert.NoError(t, tree.Push(d))
+	}
+	assert.Error(t, tree.Validate())
+	tree = NewNCMT(WithSegmentSize(256))
+	for _, d := range data {
+		assert.NoError(t, tree.Push(d))
+	}
+	assert.Error(t, tree.Validate())
+}
+
+// lockedCodec serializes calls to RSFG8, so that it can be used concurrently
+type lockedCodec struct {
+	RSFG8
+	mut *sync.Mutex
+}
+
+func (c lockedCodec) Encode(input [][]byte) ([][]byte, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.RSFG8.Encode(input)
+}
+
+func (c lockedCodec) Concurrent() bool {
+	return true
+}
+
+func TestConcurrentSegments(t *testing.T) {
+	data := mockData(64, 8)
+	build := func(setters ...Option) []byte {
+		tree := NewNCMT(append([]Option{WithSegmentSize(16)}, setters...)...)
+		for _, d := range data {
+			assert.NoError(t, tree.Push(d))
+		}
+		root, err := tree.Build()
+		assert.NoError(t, err)
+		return root
+	}
+	serial := build()
+	codec := lockedCodec{mut: &sync.Mutex{}}
+	assert.Equal(t, 4, codecWorkers(codec, 4))
+	assert.Equal(t, 1, codecWorkers(RSFG8{}, 4))
+	assert.Equal(t, serial, build(WithCodec(codec), WithParallelism(4)))
+}
@@ -0,0 +1,55 @@
+package ncmt
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// noopTracer is used when no tracer is configured
+var noopTracer = trace.NewNoopTracerProvider().Tracer("")
+
+// WithTracer records OpenTelemetry spans for Build, proof generation and
+// verification, and every encode and decode performed with these options.
+// Spans are only recorded if a tracer is set.
+func WithTracer(t trace.Tracer) Option {
+	return func(o *Options) {
+		o.Tracer = t
+	}
+}
+
+// startSpan starts a span using t, or a non recording span if t is nil
+func startSpan(ctx context.Context, t trace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if t == nil {
+		t = noopTracer
+	}
+	return t.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err, if any, and ends span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// startEncodeSpan starts a span for erasuring a layer of the tree
+func (n *NCMT) startEncodeSpan(ctx context.Context, layer int) (context.Context, trace.Span) {
+	return startSpan(ctx, n.opts.Tracer, "ncmt.Encode",
+		attribute.Int("ncmt.layer", layer),
+		attribute.String("ncmt.codec", n.opts.Codec.ID()),
+	)
+}
+
+func namespaceAttr(nID []byte) attribute.KeyValue {
+	return attribute.String("ncmt.namespace", FormatNamespace(nID))
+}
+
+func proofAttrs(p Proof) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Int("ncmt.proof.leaves", len(p.Data)),
+		attribute.Int("ncmt.proof.set_size", len(p.Set)),
+	}
+}
@@ -0,0 +1,102 @@
+package ncmt
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordingTracer keeps every span it starts in memory
+type recordingTracer struct {
+	mut   sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	trace.Span
+	name   string
+	attrs  map[attribute.Key]attribute.Value
+	failed bool
+	ended  bool
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	_, noop := noopTracer.Start(ctx, name)
+	span := &recordedSpan{Span: noop, name: name, attrs: make(map[attribute.Key]attribute.Value)}
+	cfg := trace.NewSpanStartConfig(opts...)
+	span.SetAttributes(cfg.Attributes()...)
+	t.mut.Lock()
+	t.spans = append(t.spans, span)
+	t.mut.Unlock()
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func (t *recordingTracer) named(name string) []*recordedSpan {
+	var spans []*recordedSpan
+	for _, span := range t.spans {
+		if span.name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error, _ ...trace.EventOption) {
+	s.failed = true
+}
+
+func (s *recordedSpan) End(_ ...trace.SpanEndOption) {
+	s.ended = true
+}
+
+func TestTracing(t *testing.T) {
+	tracer := &recordingTracer{}
+	tree := sharedNamespaceTree(t, WithTracer(tracer))
+
+	builds := tracer.named("ncmt.Build")
+	assert.Len(t, builds, 1)
+	assert.Equal(t, int64(16), builds[0].attrs["ncmt.leaves"].AsInt64())
+	// one encode per layer below the root
+	assert.Len(t, tracer.named("ncmt.Encode"), len(tree.layers))
+
+	proof, err := tree.ProveNamespace(mockID(4))
+	assert.NoError(t, err)
+	prove := tracer.named("ncmt.ProveNamespace")
+	assert.Len(t, prove, 1)
+	assert.Equal(t, "0x0000000000000004", prove[0].attrs["ncmt.namespace"].AsString())
+	assert.Equal(t, int64(3), prove[0].attrs["ncmt.proof.leaves"].AsInt64())
+
+	opts := tree.Options()
+	assert.True(t, Verify(tree.Root(), &opts, proof))
+	proof.Data[0][len(proof.Data[0])-1]++
+	assert.False(t, Verify(tree.Root(), &opts, proof))
+	verifies := tracer.named("ncmt.Verify")
+	assert.Len(t, verifies, 2)
+	assert.True(t, verifies[0].attrs["ncmt.valid"].AsBool())
+	assert.False(t, verifies[1].attrs["ncmt.valid"].AsBool())
+	assert.True(t, verifies[1].failed)
+
+	_, err = tree.ProveRange(3, 3)
+	assert.Error(t, err)
+	assert.True(t, tracer.named("ncmt.ProveRange")[0].failed)
+
+	samples, err := tree.Sample(rangePositions(16, 32))
+	assert.NoError(t, err)
+	_, err = Repair(tree.Root(), &opts, samples)
+	assert.NoError(t, err)
+	assert.Len(t, tracer.named("ncmt.Decode"), 1)
+
+	for _, span := range tracer.spans {
+		assert.True(t, span.ended, span.name)
+	}
+}
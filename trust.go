@@ -0,0 +1,176 @@
+package ncmt
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// VerifyFunc checks that a proof is valid for the given root
+type VerifyFunc func(root []byte, p Proof) bool
+
+// TrustedRootStore records which (root, proof) pairs have already been
+// successfully verified. Keys are opaque and generated by VerifierWithTrust.
+type TrustedRootStore interface {
+	Trusted(key []byte) bool
+	Trust(key []byte)
+}
+
+// VerifierWithTrust wraps verify so that proofs already verified against a root
+// are accepted without repeating the verification. Only successful
+// verifications are recorded in the store. opts must be the options verify
+// checks proofs with, and the parameters of opts that affect verification are
+// part of every key, so that a store shared by verifiers with different
+// parameters never accepts a proof verified under other parameters. If opts is
+// nil, the default options are used, and if verify is nil, Verifier(opts) is
+// used.
+func VerifierWithTrust(store TrustedRootStore, opts *Options, verify VerifyFunc) VerifyFunc {
+	if opts == nil {
+		opts = NewNCMT().opts
+	}
+	if verify == nil {
+		verify = Verifier(opts)
+	}
+	params := trustParams(opts)
+	return func(root []byte, p Proof) bool {
+		key := trustKey(params, root, p)
+		if store.Trusted(key) {
+			return true
+		}
+		if !verify(root, p) {
+			return false
+		}
+		store.Trust(key)
+		return true
+	}
+}
+
+// trustParams hashes the parameters of opts that affect verification. The hash
+// function is identified by its ID and by its digest of a probe, as FreshHash
+// can be set without updating the ID.
+func trustParams(opts *Options) []byte {
+	h := sha256.New()
+	writeUint64(h, uint64(opts.CommitmentVersion))
+	writeLenPrefixed(h, opts.Salt)
+	writeLenPrefixed(h, []byte(opts.HashID))
+	var digest []byte
+	if opts.checkHash() == nil {
+		digest = probeHash(opts.FreshHash)
+	}
+	writeLenPrefixed(h, digest)
+	writeUint64(h, uint64(opts.BatchSize))
+	writeUint64(h, uint64(opts.NamespaceSize))
+	leafCounts := uint64(0)
+	if opts.LeafCounts {
+		leafCounts = 1
+	}
+	writeUint64(h, leafCounts)
+	return h.Sum(nil)
+}
+
+// trustKey hashes the verification parameters and the root along with every
+// field of the proof
+func trustKey(params, root []byte, p Proof) []byte {
+	h := sha256.New()
+	writeLenPrefixed(h, params)
+	writeLenPrefixed(h, root)
+	writeUint64(h, uint64(p.Start))
+	writeUint64(h, uint64(p.End))
+	writeUint64(h, uint64(p.Width))
+	writeLenPrefixed(h, p.NamespaceID)
+	writeUint64(h, uint64(len(p.Data)))
+	for _, d := range p.Data {
+		writeLenPrefixed(h, d)
+	}
+	writeUint64(h, uint64(len(p.LeafHashes)))
+	for _, lh := range p.LeafHashes {
+		writeLenPrefixed(h, lh)
+	}
+	writeUint64(h, uint64(len(p.Set)))
+	for _, s := range p.Set {
+		writeLenPrefixed(h, s)
+	}
+	writeUint64(h, uint64(len(p.Counts)))
+	for _, c := range p.Counts {
+		writeUint64(h, c[0])
+		writeUint64(h, c[1])
+	}
+	return h.Sum(nil)
+}
+
+// MemoryTrustStore is a thread safe, in memory TrustedRootStore. Entries expire
+// after a fixed ttl, and the least recently used entry is evicted once the store
+// is full.
+type MemoryTrustStore struct {
+	mut        sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+	now        func() time.Time
+}
+
+type trustEntry struct {
+	key     string
+	expires time.Time
+}
+
+// NewMemoryTrustStore issues a new MemoryTrustStore. A ttl or maxEntries of
+// zero disables the respective bound.
+func NewMemoryTrustStore(ttl time.Duration, maxEntries int) *MemoryTrustStore {
+	return &MemoryTrustStore{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// Trusted returns true if the key was trusted and has not expired
+func (s *MemoryTrustStore) Trusted(key []byte) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	elem, found := s.entries[string(key)]
+	if !found {
+		return false
+	}
+	entry := elem.Value.(trustEntry)
+	if s.ttl > 0 && s.now().After(entry.expires) {
+		s.remove(elem)
+		return false
+	}
+	s.order.MoveToFront(elem)
+	return true
+}
+
+// Trust adds or refreshes a key, evicting the least recently used key if the
+// store is full
+func (s *MemoryTrustStore) Trust(key []byte) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	entry := trustEntry{key: string(key), expires: s.now().Add(s.ttl)}
+	if elem, found := s.entries[entry.key]; found {
+		elem.Value = entry
+		s.order.MoveToFront(elem)
+		return
+	}
+	s.entries[entry.key] = s.order.PushFront(entry)
+	if s.maxEntries > 0 && s.order.Len() > s.maxEntries {
+		s.remove(s.order.Back())
+	}
+}
+
+// Len returns the number of entries currently held, including expired entries
+// that have not yet been removed
+func (s *MemoryTrustStore) Len() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.order.Len()
+}
+
+func (s *MemoryTrustStore) remove(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.entries, elem.Value.(trustEntry).key)
+}
@@ -0,0 +1,86 @@
+package ncmt
+
+import (
+	"crypto/sha512"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifierWithTrust(t *testing.T) {
+	calls := 0
+	valid := true
+	verify := func(root []byte, p Proof) bool {
+		calls++
+		return valid
+	}
+	store := NewMemoryTrustStore(time.Minute, 2)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	verifier := VerifierWithTrust(store, nil, verify)
+
+	root := []byte{1, 2, 3}
+	proof := Proof{Set: [][]byte{{4}, {5}}, Start: 1, End: 2, Width: 4}
+
+	// the first verification is performed and cached
+	assert.True(t, verifier(root, proof))
+	assert.True(t, verifier(root, proof))
+	assert.Equal(t, 1, calls)
+
+	// a different proof is not covered by the cache
+	otherProof := Proof{Set: [][]byte{{4}, {6}}, Start: 1, End: 2, Width: 4}
+	valid = false
+	assert.False(t, verifier(root, otherProof))
+	assert.False(t, verifier(root, otherProof))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 1, store.Len())
+
+	// entries expire after the ttl
+	now = now.Add(2 * time.Minute)
+	assert.False(t, verifier(root, proof))
+	assert.Equal(t, 4, calls)
+}
+
+func TestVerifierWithTrustParams(t *testing.T) {
+	tree := sharedNamespaceTree(t)
+	root := tree.Root()
+	proof, err := tree.ProveNamespace(mockID(4))
+	assert.NoError(t, err)
+
+	// a store shared by verifiers with different parameters
+	store := NewMemoryTrustStore(0, 0)
+	assert.True(t, VerifierWithTrust(store, nil, nil)(root, proof))
+	assert.Equal(t, 1, store.Len())
+
+	for name, setter := range map[string]Option{
+		"salt":    WithSalt([]byte("salt")),
+		"version": WithCommitmentVersion(CommitmentV1),
+		"hash":    func(o *Options) { o.FreshHash = sha512.New512_256 },
+	} {
+		calls := 0
+		opts := NewNCMT(setter).opts
+		verify := func(root []byte, p Proof) bool {
+			calls++
+			return Verify(root, opts, p)
+		}
+		// the proof trusted under the default parameters is verified again,
+		// and rejected
+		assert.False(t, VerifierWithTrust(store, opts, verify)(root, proof), name)
+		assert.Equal(t, 1, calls, name)
+	}
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestMemoryTrustStoreEviction(t *testing.T) {
+	store := NewMemoryTrustStore(0, 2)
+	store.Trust([]byte{1})
+	store.Trust([]byte{2})
+	// touch the first key so that the second is least recently used
+	assert.True(t, store.Trusted([]byte{1}))
+	store.Trust([]byte{3})
+	assert.Equal(t, 2, store.Len())
+	assert.True(t, store.Trusted([]byte{1}))
+	assert.False(t, store.Trusted([]byte{2}))
+	assert.True(t, store.Trusted([]byte{3}))
+}
@@ -0,0 +1,72 @@
+package ncmt
+
+import (
+	"fmt"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// NCMTOf wraps an NCMT so that applications can push and retrieve domain
+// objects (transactions, blobs, etc) directly instead of plumbing bytes
+type NCMTOf[T any] struct {
+	tree   *NCMT
+	encode func(T) (namespace.ID, []byte)
+	decode func(namespace.ID, []byte) (T, error)
+}
+
+// NewNCMTOf issues a new typed NCMT. encode maps a value to the namespace and
+// raw data stored in its leaf, and decode reverses that mapping.
+func NewNCMTOf[T any](
+	encode func(T) (namespace.ID, []byte),
+	decode func(namespace.ID, []byte) (T, error),
+	setters ...Option,
+) *NCMTOf[T] {
+	return &NCMTOf[T]{
+		tree:   NewNCMT(setters...),
+		encode: encode,
+		decode: decode,
+	}
+}
+
+// Push encodes v and adds it to the leaves of the underlying tree. The same
+// ordering rules as NCMT.Push apply.
+func (n *NCMTOf[T]) Push(v T) error {
+	id, data := n.encode(v)
+	return n.tree.Push(NamespacedData{ID: id, Payload: data})
+}
+
+// Build builds the underlying tree and returns its root
+func (n *NCMTOf[T]) Build() ([]byte, error) {
+	return n.tree.Build()
+}
+
+// Root returns the root hash of the underlying tree
+func (n *NCMTOf[T]) Root() []byte {
+	return n.tree.Root()
+}
+
+// Get decodes the original leaf at idx
+func (n *NCMTOf[T]) Get(idx uint) (T, error) {
+	data, err := n.tree.Get(idx)
+	if err != nil {
+		var empty T
+		return empty, err
+	}
+	return n.Decode(data)
+}
+
+// Decode converts namespaced data, such as data retrieved from a proof or
+// another tree, into a typed value
+func (n *NCMTOf[T]) Decode(data namespace.Data) (T, error) {
+	v, err := n.decode(data.NamespaceID(), data.Data())
+	if err != nil {
+		var empty T
+		return empty, fmt.Errorf("failure to decode leaf data: %s", err)
+	}
+	return v, nil
+}
+
+// Tree returns the underlying untyped tree
+func (n *NCMTOf[T]) Tree() *NCMT {
+	return n.tree
+}
@@ -0,0 +1,62 @@
+package ncmt
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/lazyledger/nmt/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockTx struct {
+	sender uint64
+	amount uint64
+}
+
+func encodeMockTx(tx mockTx) (namespace.ID, []byte) {
+	id := make(namespace.ID, 8)
+	binary.BigEndian.PutUint64(id, tx.sender)
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, tx.amount)
+	return id, data
+}
+
+func decodeMockTx(id namespace.ID, data []byte) (mockTx, error) {
+	if len(id) != 8 || len(data) != 8 {
+		return mockTx{}, errors.New("invalid mock tx")
+	}
+	return mockTx{
+		sender: binary.BigEndian.Uint64(id),
+		amount: binary.BigEndian.Uint64(data),
+	}, nil
+}
+
+func TestNCMTOf(t *testing.T) {
+	tree := NewNCMTOf(encodeMockTx, decodeMockTx)
+	var txs []mockTx
+	for i := 0; i < 16; i++ {
+		tx := mockTx{sender: uint64(i), amount: uint64(i * 100)}
+		txs = append(txs, tx)
+		err := tree.Push(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := tree.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, tree.Tree().Root(), root)
+
+	// check that the typed data survives the round trip
+	for i, tx := range txs {
+		got, err := tree.Get(uint(i))
+		assert.NoError(t, err)
+		assert.Equal(t, tx, got)
+	}
+
+	// erasured leaves are not accessible through Get
+	_, err = tree.Get(uint(len(txs)))
+	assert.Error(t, err)
+}
@@ -0,0 +1,173 @@
+package ncmt
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+//go:embed vectors.json
+var vectorsJSON []byte
+
+// Vector describes a tree built with the default hash and codec, along with
+// the root it must produce and proofs of its leaves. Byte fields are hex
+// encoded.
+type Vector struct {
+	Name          string   `json:"name"`
+	BatchSize     int      `json:"batch_size"`
+	NamespaceSize int      `json:"namespace_size"`
+	LeafCounts    bool     `json:"leaf_counts"`
+	Leaves        []string `json:"leaves"`
+	Root          string   `json:"root"`
+
+	// CommitmentVersion is omitted for CommitmentV0
+	CommitmentVersion uint8 `json:"commitment_version,omitempty"`
+	// Salt is omitted when empty
+	Salt string `json:"salt,omitempty"`
+	// Proofs are checked against Root
+	Proofs []ProofVector `json:"proofs,omitempty"`
+}
+
+// ProofVector is a proof of the tree of a Vector, hex encoded using
+// Proof.MarshalCBOR
+type ProofVector struct {
+	// Kind is one of leaf, parity, range, namespace or absence
+	Kind  string `json:"kind"`
+	Proof string `json:"proof"`
+	// Valid is false for proofs that have been tampered with, which must be
+	// rejected
+	Valid bool `json:"valid"`
+}
+
+// VectorsJSON returns the raw embedded test vectors, for implementations that
+// consume them outside of go
+func VectorsJSON() []byte {
+	return append([]byte{}, vectorsJSON...)
+}
+
+// Vectors decodes the embedded test vectors
+func Vectors() ([]Vector, error) {
+	var vectors []Vector
+	err := json.Unmarshal(vectorsJSON, &vectors)
+	if err != nil {
+		return nil, fmt.Errorf("failure to decode test vectors: %s", err)
+	}
+	return vectors, nil
+}
+
+// RootFunc computes the root of the tree described by a Vector
+type RootFunc func(v Vector) ([]byte, error)
+
+// VectorProofFunc decodes the CBOR encoded proof and checks it against the
+// root of the tree described by a Vector
+type VectorProofFunc func(v Vector, proof []byte) (bool, error)
+
+// VectorRoot computes the root of a Vector using this package
+func VectorRoot(v Vector) ([]byte, error) {
+	tree, err := vectorTree(v)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Build()
+}
+
+// VectorVerify verifies a proof of a Vector using this package
+func VectorVerify(v Vector, proof []byte) (bool, error) {
+	setters, err := vectorOptions(v)
+	if err != nil {
+		return false, err
+	}
+	root, err := hex.DecodeString(v.Root)
+	if err != nil {
+		return false, fmt.Errorf("invalid root: %s", err)
+	}
+	var p Proof
+	err = p.UnmarshalCBOR(proof)
+	if err != nil {
+		return false, err
+	}
+	opts := NewNCMT(setters...).Options()
+	return Verify(root, &opts, p), nil
+}
+
+// vectorOptions returns the options of the tree described by v
+func vectorOptions(v Vector) ([]Option, error) {
+	salt, err := hex.DecodeString(v.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %s", err)
+	}
+	return []Option{WithSalt(salt), func(o *Options) {
+		o.BatchSize = v.BatchSize
+		o.NamespaceSize = namespace.IDSize(v.NamespaceSize)
+		o.LeafCounts = v.LeafCounts
+		o.CommitmentVersion = CommitmentVersion(v.CommitmentVersion)
+	}}, nil
+}
+
+// vectorTree pushes the leaves of v to a new tree
+func vectorTree(v Vector) (*NCMT, error) {
+	setters, err := vectorOptions(v)
+	if err != nil {
+		return nil, err
+	}
+	tree := NewNCMT(setters...)
+	for i, rawLeaf := range v.Leaves {
+		leaf, err := hex.DecodeString(rawLeaf)
+		if err != nil {
+			return nil, fmt.Errorf("invalid leaf %d: %s", i, err)
+		}
+		err = tree.Push(namespace.NewPrefixedData(namespace.IDSize(v.NamespaceSize), leaf))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tree, nil
+}
+
+// RunVectorTests checks that root produces the expected root for every
+// embedded test vector, and that verify accepts exactly the valid proofs of
+// each. Pass VectorRoot and VectorVerify to test this package, or wrappers
+// around another implementation to check compatibility. If verify is nil,
+// only roots are checked.
+func RunVectorTests(t testing.TB, root RootFunc, verify VectorProofFunc) {
+	vectors, err := Vectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		expected, err := hex.DecodeString(v.Root)
+		if err != nil {
+			t.Fatalf("%s: invalid expected root: %s", v.Name, err)
+		}
+		got, err := root(v)
+		if err != nil {
+			t.Errorf("%s: %s", v.Name, err)
+			continue
+		}
+		if !bytes.Equal(expected, got) {
+			t.Errorf("%s: unexpected root: expected %s, got %s", v.Name, FormatRoot(expected), FormatRoot(got))
+		}
+		if verify == nil {
+			continue
+		}
+		for i, pv := range v.Proofs {
+			proof, err := hex.DecodeString(pv.Proof)
+			if err != nil {
+				t.Fatalf("%s: invalid proof %d: %s", v.Name, i, err)
+			}
+			valid, err := verify(v, proof)
+			if err != nil {
+				t.Errorf("%s: %s proof %d: %s", v.Name, pv.Kind, i, err)
+				continue
+			}
+			if valid != pv.Valid {
+				t.Errorf("%s: %s proof %d: expected valid to be %t, got %t", v.Name, pv.Kind, i, pv.Valid, valid)
+			}
+		}
+	}
+}
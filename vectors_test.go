@@ -0,0 +1,170 @@
+package ncmt
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+)
+
+var updateVectors = flag.Bool("update-vectors", false, "regenerate vectors.json")
+
+func TestVectors(t *testing.T) {
+	if *updateVectors {
+		writeVectors(t)
+	}
+	RunVectorTests(t, VectorRoot, VectorVerify)
+
+	vectors, err := Vectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		for _, pv := range v.Proofs {
+			raw, err := hex.DecodeString(pv.Proof)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var proof Proof
+			err = proof.UnmarshalCBOR(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proof.Absence() != (pv.Kind == "absence") {
+				t.Errorf("%s: %s proof has absence %t", v.Name, pv.Kind, proof.Absence())
+			}
+		}
+	}
+}
+
+// writeVectors regenerates vectors.json using deterministic leaf data
+func writeVectors(t *testing.T) {
+	params := []struct {
+		name          string
+		leafCount     int
+		leafSize      int
+		batchSize     int
+		namespaceSize int
+		namespaces    int
+		leafCounts    bool
+		version       CommitmentVersion
+		salt          string
+	}{
+		{"minimal", 4, 4, 4, 8, 4, false, CommitmentV0, ""},
+		{"unique-namespaces", 16, 32, 4, 8, 16, false, CommitmentV0, ""},
+		{"shared-namespaces", 32, 16, 4, 8, 5, false, CommitmentV0, ""},
+		{"large-batch", 64, 8, 8, 8, 12, false, CommitmentV0, ""},
+		{"small-namespace", 16, 16, 4, 1, 3, false, CommitmentV0, ""},
+		{"leaf-counts", 32, 16, 4, 8, 5, true, CommitmentV0, ""},
+		{"max-leaves", 128, 64, 4, 8, 30, false, CommitmentV0, ""},
+		{"v1-shared-namespaces", 32, 16, 4, 8, 5, false, CommitmentV1, ""},
+		{"v1-leaf-counts", 32, 16, 4, 8, 5, true, CommitmentV1, ""},
+		{"salted", 16, 16, 4, 8, 4, false, CommitmentV0, "0000000000000064"},
+		{"v1-salted", 16, 16, 4, 8, 4, false, CommitmentV1, "0000000000000064"},
+	}
+	var vectors []Vector
+	for _, p := range params {
+		v := Vector{
+			Name:              p.name,
+			BatchSize:         p.batchSize,
+			NamespaceSize:     p.namespaceSize,
+			LeafCounts:        p.leafCounts,
+			CommitmentVersion: uint8(p.version),
+			Salt:              p.salt,
+		}
+		for i := 0; i < p.leafCount; i++ {
+			// spread the namespaces evenly and in order across the leaves
+			id := make([]byte, 8)
+			binary.BigEndian.PutUint64(id, uint64(i*p.namespaces/p.leafCount))
+			id = id[8-p.namespaceSize:]
+			data := make([]byte, 0, p.leafSize)
+			for counter := 0; len(data) < p.leafSize; counter++ {
+				seed := sha256.Sum256([]byte(p.name + string(rune(i)) + string(rune(counter))))
+				data = append(data, seed[:]...)
+			}
+			v.Leaves = append(v.Leaves, hex.EncodeToString(append(id, data[:p.leafSize]...)))
+		}
+		tree, err := vectorTree(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := tree.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		v.Root = hex.EncodeToString(root)
+		v.Proofs = writeProofVectors(t, tree, p.namespaces)
+		vectors = append(vectors, v)
+	}
+	raw, err := json.MarshalIndent(vectors, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile("vectors.json", append(raw, '\n'), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectorsJSON = raw
+}
+
+// writeProofVectors proves a leaf, a parity leaf, a range, a namespace and an
+// absent namespace of tree, along with a tampered copy of the leaf proof
+func writeProofVectors(t *testing.T, tree *NCMT, namespaces int) []ProofVector {
+	width := tree.OriginalWidth()
+	leaf, err := tree.ProveLeaf(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parity, err := tree.ProveLeaf(width + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng, err := tree.ProveRange(1, width/2+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nID, err := tree.Get(width / 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns, err := tree.ProveNamespace(nID.NamespaceID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// namespaces are numbered from 0, so the next one is absent
+	absentID := make([]byte, 8)
+	binary.BigEndian.PutUint64(absentID, uint64(namespaces))
+	absence, err := tree.ProveNamespace(absentID[8-int(tree.opts.NamespaceSize):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered, err := tree.ProveLeaf(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered.Data[0][len(tampered.Data[0])-1]++
+
+	var vectors []ProofVector
+	for _, pv := range []struct {
+		kind  string
+		proof Proof
+		valid bool
+	}{
+		{"leaf", leaf, true},
+		{"parity", parity, true},
+		{"range", rng, true},
+		{"namespace", ns, true},
+		{"absence", absence, true},
+		{"leaf", tampered, false},
+	} {
+		raw, err := pv.proof.MarshalCBOR()
+		if err != nil {
+			t.Fatal(err)
+		}
+		vectors = append(vectors, ProofVector{Kind: pv.kind, Proof: hex.EncodeToString(raw), Valid: pv.valid})
+	}
+	return vectors
+}
@@ -0,0 +1,159 @@
+package ncmt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/lazyledger/nmt/namespace"
+)
+
+// WireParams describe a tree well enough to compute the encoded sizes of its
+// proofs and header without the tree itself
+type WireParams struct {
+	// Width is the number of original leaves
+	Width         uint
+	BatchSize     int
+	NamespaceSize namespace.IDSize
+	// ShareSize is the size of a leaf's data, excluding its namespace
+	ShareSize int
+	// DigestSize is the output size of the hash function
+	DigestSize int
+	LeafCounts bool
+
+	// The remaining parameters only affect the size of headers
+	CommitmentVersion CommitmentVersion
+	Codec             string
+	Hash              string
+	SaltSize          int
+	SegmentSize       int
+	// FilterSize is the size of the namespace filter in bytes, or 0 if
+	// headers have no filter
+	FilterSize int
+}
+
+// WireParams returns the parameters of a built tree
+func (n *NCMT) WireParams() (WireParams, error) {
+	if len(n.layers) == 0 {
+		return WireParams{}, errors.New("tree must be built before computing wire sizes")
+	}
+	hashID, err := n.opts.resolveHashID()
+	if err != nil {
+		return WireParams{}, err
+	}
+	params := WireParams{
+		Width:             n.originalWidth,
+		BatchSize:         n.opts.BatchSize,
+		NamespaceSize:     n.opts.NamespaceSize,
+		ShareSize:         len(n.leaves[0].data.Data()),
+		DigestSize:        n.opts.FreshHash().Size(),
+		LeafCounts:        n.opts.LeafCounts,
+		CommitmentVersion: n.opts.CommitmentVersion,
+		Codec:             n.opts.Codec.ID(),
+		Hash:              hashID,
+		SaltSize:          len(n.opts.Salt),
+		SegmentSize:       n.opts.SegmentSize,
+	}
+	if filter := n.namespaceFilter(); filter != nil {
+		params.FilterSize = len(filter.Bits)
+	}
+	return params, nil
+}
+
+// ProofWireSize returns the size of the CBOR encoding of a proof of the
+// leaves [start, end) of the extended leaf layer, which is a namespace proof if
+// namespaceProof is set. Sizes are computed by encoding a proof of the same
+// shape, so they always agree with MarshalCBOR. Leaf counts, whose encoding
+// depends on their values, are assumed to take their largest encoding, which
+// makes the size an upper bound if they are committed.
+func ProofWireSize(params WireParams, start, end uint, namespaceProof bool) (int, error) {
+	batchSize := uint(params.BatchSize / 2)
+	err := checkBatchSize(params.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	err = checkProofRange(start, end, params.Width)
+	if err != nil {
+		return 0, err
+	}
+	nsSize := int(params.NamespaceSize)
+	leafHashSize := nsSize + params.DigestSize
+	nodeHashSize := 2*nsSize + params.DigestSize
+
+	proof := Proof{Start: start, End: end, Width: params.Width}
+	for i := start; i < end; i++ {
+		proof.Data = append(proof.Data, make([]byte, nsSize+params.ShareSize))
+	}
+	known := rangePositions(start, end)
+	width := params.Width
+	for level := 0; width > 1; level++ {
+		if width%batchSize != 0 {
+			return 0, fmt.Errorf("width %d is not divisible by the batch size", width)
+		}
+		siblings, parents := planLevel(known, width, batchSize)
+		for _, sibling := range siblings {
+			if level == 0 {
+				proof.Set = append(proof.Set, make([]byte, leafHashSize))
+				continue
+			}
+			proof.Set = append(proof.Set, make([]byte, nodeHashSize))
+			if params.LeafCounts && sibling < width {
+				proof.Counts = append(proof.Counts, [2]uint64{math.MaxUint64, math.MaxUint64})
+			}
+		}
+		known = parents
+		width = width / batchSize
+	}
+	if namespaceProof {
+		proof.NamespaceID = make([]byte, nsSize)
+	}
+	return proofSize(proof)
+}
+
+// SampleWireSize returns the size of the largest sample of the tree, which is
+// a proof of a single leaf of the extended leaf layer
+func SampleWireSize(params WireParams) (int, error) {
+	// samples only differ in the encoding of their position, which is largest
+	// for the last leaf
+	if params.Width == 0 {
+		return 0, errors.New("invalid width 0")
+	}
+	last := 2*params.Width - 1
+	return ProofWireSize(params, last, last+1, false)
+}
+
+// HeaderWireSize returns the size of the CBOR encoding of the tree's header
+func HeaderWireSize(params WireParams) (int, error) {
+	batchSize := uint(params.BatchSize / 2)
+	err := checkBatchSize(params.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	depth := 0
+	for width := params.Width; width > 1; width = width / batchSize {
+		depth++
+	}
+	header := TreeHeader{
+		Root:              make([]byte, 2*int(params.NamespaceSize)+params.DigestSize),
+		CommitmentVersion: params.CommitmentVersion,
+		Codec:             params.Codec,
+		BatchSize:         params.BatchSize,
+		NamespaceSize:     params.NamespaceSize,
+		LeafCounts:        params.LeafCounts,
+		Salt:              make([]byte, params.SaltSize),
+		Hash:              params.Hash,
+		OriginalWidth:     params.Width,
+		ExtendedWidth:     2 * params.Width,
+		Depth:             depth,
+		SegmentSize:       params.SegmentSize,
+	}
+	if params.FilterSize > 0 {
+		// the number of hashes is at most 16, so its encoding has a fixed size
+		header.NamespaceFilter = &NamespaceFilter{Bits: make([]byte, params.FilterSize), Hashes: 1}
+	}
+	raw, err := header.MarshalCBOR()
+	if err != nil {
+		return 0, err
+	}
+	return len(raw), nil
+}
@@ -0,0 +1,79 @@
+package ncmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWireSizes(t *testing.T) {
+	for _, setters := range [][]Option{
+		nil,
+		{WithCommitmentVersion(CommitmentV1), WithSalt([]byte("height-100"))},
+		{WithNamespaceFilter(10), WithSegmentSize(8)},
+	} {
+		tree := sharedNamespaceTree(t, setters...)
+		params, err := tree.WireParams()
+		assert.NoError(t, err)
+
+		for _, r := range [][2]uint{{0, 1}, {3, 9}, {0, 16}, {16, 32}, {31, 32}} {
+			proof, err := tree.ProveRange(r[0], r[1])
+			assert.NoError(t, err)
+			size, err := ProofWireSize(params, r[0], r[1], false)
+			assert.NoError(t, err)
+			raw, err := proof.MarshalCBOR()
+			assert.NoError(t, err)
+			assert.Equal(t, len(raw), size, "range %v", r)
+		}
+
+		proof, err := tree.ProveNamespace(mockID(4))
+		assert.NoError(t, err)
+		size, err := ProofWireSize(params, proof.Start, proof.End, true)
+		assert.NoError(t, err)
+		actual, err := proofSize(proof)
+		assert.NoError(t, err)
+		assert.Equal(t, actual, size)
+
+		maxSample, err := SampleWireSize(params)
+		assert.NoError(t, err)
+		samples, err := tree.Sample(rangePositions(0, 32))
+		assert.NoError(t, err)
+		for _, sample := range samples {
+			size, err := proofSize(sample)
+			assert.NoError(t, err)
+			assert.LessOrEqual(t, size, maxSample)
+		}
+		last, err := proofSize(samples[31])
+		assert.NoError(t, err)
+		assert.Equal(t, last, maxSample)
+
+		header, err := tree.Header()
+		assert.NoError(t, err)
+		raw, err := header.MarshalCBOR()
+		assert.NoError(t, err)
+		size, err = HeaderWireSize(params)
+		assert.NoError(t, err)
+		assert.Equal(t, len(raw), size)
+
+		var decoded TreeHeader
+		assert.NoError(t, decoded.UnmarshalCBOR(raw))
+		assert.Equal(t, header, decoded)
+	}
+
+	// committed leaf counts are bounded by their largest encoding
+	tree := sharedNamespaceTree(t, WithLeafCounts())
+	params, err := tree.WireParams()
+	assert.NoError(t, err)
+	proof, err := tree.ProveRange(3, 9)
+	assert.NoError(t, err)
+	actual, err := proofSize(proof)
+	assert.NoError(t, err)
+	size, err := ProofWireSize(params, 3, 9, false)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, size, actual)
+
+	_, err = ProofWireSize(params, 15, 17, false)
+	assert.Error(t, err)
+	_, err = NewNCMT().WireParams()
+	assert.Error(t, err)
+}